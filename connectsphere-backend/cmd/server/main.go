@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"connectsphere-backend/internal/api"
+	"connectsphere-backend/internal/config"
+	"connectsphere-backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build details, set at build time with:
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse --short HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
+)
+
+func main() {
+	configFile := flag.String("config", "", "optional YAML or JSON config file; environment variables override it")
+	flag.Parse()
+
+	log.Printf("ConnectSphere %s (commit %s, built %s)", version, commit, buildTime)
+
+	// Load configuration
+	cfg := config.Load(*configFile)
+	gin.SetMode(cfg.GinMode)
+
+	// Connect to database
+	db, err := database.New(cfg.DatabaseURL, database.PoolOptions{
+		MaxConns:        int32(cfg.DBMaxConns),
+		MinConns:        int32(cfg.DBMinConns),
+		MaxConnLifetime: cfg.DBMaxConnLifetime,
+		MaxConnIdleTime: cfg.DBMaxConnIdleTime,
+		SSLMode:         cfg.DBSSLMode,
+
+		SlowQueryThreshold: time.Duration(cfg.DBTraceSlowMS) * time.Millisecond,
+		CountQueries:       cfg.CountDBQueries(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Create or drop the display name index to match UNIQUE_DISPLAY_NAMES
+	if err := db.SetUniqueDisplayNames(context.Background(), cfg.UniqueDisplayNames); err != nil {
+		log.Fatalf("Failed to apply UNIQUE_DISPLAY_NAMES (do existing users share a display name?): %v", err)
+	}
+
+	// Log database outages and recoveries in the background
+	go db.MonitorHealth(context.Background(), cfg.DBHealthCheckInterval)
+
+	// Set up API server
+	server := api.NewServer(db, cfg)
+	server.SetBuildInfo(api.BuildInfo{Version: version, Commit: commit, BuildTime: buildTime})
+	router := server.SetupRoutes()
+
+	// Cancel stale pending connection requests, if CONNECTION_REQUEST_EXPIRY is set
+	go server.ExpireConnectionRequests(context.Background(), time.Hour)
+
+	addr := cfg.ListenAddr()
+	log.Printf("Server listening on %s", addr)
+	if err := router.Run(addr); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
@@ -10,6 +10,13 @@ CREATE TABLE users (
     display_name TEXT NOT NULL,
     email TEXT UNIQUE NOT NULL,
     hashed_password TEXT NOT NULL,
+    profile_visibility TEXT NOT NULL DEFAULT 'public' CHECK (profile_visibility IN ('public', 'connections_only', 'private')),
+    discoverable_by_email BOOLEAN NOT NULL DEFAULT TRUE,
+    connection_request_policy TEXT NOT NULL DEFAULT 'everyone' CHECK (connection_request_policy IN ('everyone', 'connections_of_connections', 'nobody')),
+    show_connections BOOLEAN NOT NULL DEFAULT TRUE, -- connections may list this user's connections
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    status_message TEXT CHECK (char_length(status_message) BETWEEN 1 AND 100),
+    status_expires_at TIMESTAMPTZ, -- NULL keeps the status until it is changed
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
@@ -19,18 +26,136 @@ CREATE TABLE user_connections (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     addressee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-    status TEXT NOT NULL CHECK (status IN ('pending', 'accepted')),
+    status TEXT NOT NULL CHECK (status IN ('pending', 'accepted', 'declined')),
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     UNIQUE(requester_id, addressee_id)
 );
 
--- Indexes for better performance
-CREATE INDEX idx_users_username ON users(username);
-CREATE INDEX idx_users_email ON users(email);
-CREATE INDEX idx_user_connections_requester ON user_connections(requester_id);
-CREATE INDEX idx_user_connections_addressee ON user_connections(addressee_id);
-CREATE INDEX idx_user_connections_status ON user_connections(status);
+-- Every connection request ever sent, kept for rate limiting even after declines/removals
+CREATE TABLE connection_request_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    addressee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Blocked users (blocker no longer sees or interacts with blocked, and vice versa)
+CREATE TABLE blocked_users (
+    blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+-- Pending email changes (the old email stays active until the new one is confirmed)
+CREATE TABLE email_change_requests (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    new_email TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- Login sessions (one per issued token, revocable by the user)
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+-- Accounts signed in through an external identity provider (SSO), keyed by the token's iss/sub
+CREATE TABLE user_identities (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject)
+);
+
+-- In-app notifications (payload is the event body, e.g. the other user's public profile)
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Single-use invite codes for REGISTRATION_MODE=invite
+CREATE TABLE invite_codes (
+    code TEXT PRIMARY KEY,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    used_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    used_at TIMESTAMPTZ
+);
+
+-- Append-only audit trail of connection status changes between two users. There are
+-- no foreign keys so the history survives the rows it describes.
+CREATE TABLE connection_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    requester_id UUID NOT NULL,
+    addressee_id UUID NOT NULL,
+    event TEXT NOT NULL CHECK (event IN ('created', 'accepted', 'declined', 'removed')),
+    actor_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+-- Private tags (e.g. 'close') a user puts on their accepted connections. Removed
+-- with the connection, so they don't come back if the two users reconnect.
+CREATE TABLE connection_tags (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    connection_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tag VARCHAR(32) NOT NULL CHECK (tag ~ '^[a-z0-9_-]+$'),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, connection_user_id, tag)
+);
+
+-- Previous display names, kept for moderation and to rate-limit changes
+CREATE TABLE display_name_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_display_name TEXT NOT NULL,
+    new_display_name TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Indexes for better performance. Query -> index mapping:
+--   GetUserByEmail, FindUserByEmail, IsEmailTaken       -> idx_users_email_lower
+--   GetUserByUsername (login, registration checks)      -> idx_users_username_lower
+--   admin ListUsers created_at filters and sorting,
+--   admin stats signups per day/week                    -> idx_users_created_at
+--   admin stats active users (recent logins)            -> idx_sessions_created_at
+--   GetConnection / RelationshipState (pair lookups)    -> user_connections UNIQUE(requester_id, addressee_id)
+--   GetUserConnections, CountMutualConnections, visibility checks,
+--   GetPendingConnectionRequests (side + status)        -> idx_user_connections_requester_status / _addressee_status
+--   CountConnectionRequestsSince                        -> idx_connection_request_log_requester
+--   ListNotifications                                   -> idx_notifications_user_created
+--   ListConnectionEvents (either direction of a pair)   -> idx_connection_events_pair
+--   display name history and change cooldown            -> idx_display_name_history_user
+--   connection tags (lists, ?tag= filter, limit checks)  -> connection_tags PRIMARY KEY(user_id, connection_user_id, tag)
+-- Search uses LIKE '%q%' on LOWER(username/display_name), which no btree index can serve.
+-- The LOWER() unique indexes also stop "Alice" and "alice" registering as separate accounts.
+CREATE UNIQUE INDEX idx_users_email_lower ON users(LOWER(email));
+CREATE UNIQUE INDEX idx_users_username_lower ON users(LOWER(username));
+CREATE INDEX idx_users_created_at ON users(created_at);
+CREATE INDEX idx_user_connections_requester_status ON user_connections(requester_id, status);
+CREATE INDEX idx_user_connections_addressee_status ON user_connections(addressee_id, status);
+CREATE INDEX idx_sessions_user ON sessions(user_id);
+CREATE INDEX idx_sessions_created_at ON sessions(created_at);
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_connection_request_log_requester ON connection_request_log(requester_id, created_at);
+CREATE INDEX idx_blocked_users_blocked ON blocked_users(blocked_id);
+CREATE INDEX idx_display_name_history_user ON display_name_history(user_id, changed_at DESC);
+CREATE INDEX idx_connection_events_pair ON connection_events(requester_id, addressee_id, created_at);
 
 -- Function to update updated_at timestamp
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -47,3 +172,14 @@ CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
 
 CREATE TRIGGER update_user_connections_updated_at BEFORE UPDATE ON user_connections
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Keep connection_events append-only
+CREATE OR REPLACE FUNCTION reject_connection_event_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'connection_events is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER connection_events_append_only BEFORE UPDATE OR DELETE ON connection_events
+    FOR EACH ROW EXECUTE FUNCTION reject_connection_event_changes();
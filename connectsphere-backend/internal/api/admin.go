@@ -0,0 +1,212 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	"connectsphere-backend/internal/database"
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// adminMiddleware only lets administrators through. It must run after authMiddleware.
+func (s *Server) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		user, err := s.db.GetUserByID(c.Request.Context(), userID)
+		if err != nil || !user.IsAdmin {
+			c.JSON(http.StatusForbidden, errorResponse(c, "forbidden", "Administrator access required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Admin handlers
+
+func (s *Server) adminListUsers(c *gin.Context) {
+	filter := database.ListUsersFilter{
+		SortBy:     c.DefaultQuery("sort", "created_at"),
+		Descending: c.DefaultQuery("order", "desc") == "desc",
+		Limit:      50,
+	}
+
+	if filter.SortBy != "created_at" && filter.SortBy != "username" {
+		c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Parameter 'sort' must be 'created_at' or 'username'"))
+		return
+	}
+
+	for param, target := range map[string]*time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Parameter '"+param+"' must be an RFC 3339 timestamp"))
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 200 {
+			filter.Limit = parsedLimit
+		}
+	}
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			filter.Offset = parsedOffset
+		}
+	}
+
+	users, total, err := s.db.ListUsers(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to list users"))
+		return
+	}
+
+	respondList(c, models.ListResponse[models.UserAuth]{
+		Data: users,
+		Pagination: models.Pagination{
+			Limit:  filter.Limit,
+			Offset: filter.Offset,
+			Total:  &total,
+		},
+	})
+}
+
+func (s *Server) adminCreateInviteCode(c *gin.Context) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.CreateInviteCodeRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInHours > 0 {
+		expiry := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &expiry
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to generate invite code"))
+		return
+	}
+
+	invite, err := s.db.CreateInviteCode(c.Request.Context(), code, adminID, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to create invite code"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Message: "Invite code created",
+		Data:    invite,
+	})
+}
+
+func (s *Server) adminListInviteCodes(c *gin.Context) {
+	limit := 50
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 200 {
+			limit = parsedLimit
+		}
+	}
+	offset := 0
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	invites, total, err := s.db.ListInviteCodes(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to list invite codes"))
+		return
+	}
+
+	respondList(c, models.ListResponse[models.InviteCode]{
+		Data: invites,
+		Pagination: models.Pagination{
+			Limit:  limit,
+			Offset: offset,
+			Total:  &total,
+		},
+	})
+}
+
+func (s *Server) adminListConnectionEvents(c *gin.Context) {
+	events, err := s.db.ListConnectionEvents(c.Request.Context(), uuidParam(c, "user_id"), uuidParam(c, "other_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to list connection events"))
+		return
+	}
+
+	respondList(c, models.NewListResponse(events))
+}
+
+// adminDisplayNameHistory lists a user's past display names, newest first, for
+// reviewing impersonation reports
+func (s *Server) adminDisplayNameHistory(c *gin.Context) {
+	userID := uuidParam(c, "user_id")
+
+	changes, err := s.db.ListDisplayNameHistory(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get display name history"))
+		return
+	}
+
+	respondList(c, models.NewListResponse(changes))
+}
+
+// adminStats reports growth and activity. The aggregates scan whole tables, so
+// results are cached briefly; concurrent requests wait for one computation.
+func (s *Server) adminStats(c *gin.Context) {
+	s.adminStatsMu.Lock()
+	defer s.adminStatsMu.Unlock()
+
+	if s.cachedAdminStats == nil || time.Since(s.cachedAdminStats.GeneratedAt) >= s.cfg.AdminStatsCacheTTL {
+		stats, err := s.db.GetAdminStats(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get stats"))
+			return
+		}
+		stats.GeneratedAt = time.Now()
+		s.cachedAdminStats = stats
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stats retrieved successfully",
+		Data:    s.cachedAdminStats,
+	})
+}
+
+// adminEventStats reports live event stream load, including how many streams were
+// disconnected for falling too far behind (EVENT_SUBSCRIBER_BUFFER)
+func (s *Server) adminEventStats(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Event stats retrieved successfully",
+		Data:    s.hub.Stats(),
+	})
+}
+
+// generateInviteCode returns a random 12-character URL-safe code
+func generateInviteCode() (string, error) {
+	buf := make([]byte, 9)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
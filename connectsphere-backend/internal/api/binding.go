@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report fields by their JSON names so details match the request body
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON decodes and validates the request body into obj. On failure it writes a
+// 400 and returns false, so handlers can simply return.
+func bindJSON(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		bindError(c, err)
+		return false
+	}
+	return true
+}
+
+// bindOptionalJSON is bindJSON for endpoints whose body may be omitted entirely
+func bindOptionalJSON(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindJSON(obj); err != nil && !errors.Is(err, io.EOF) {
+		bindError(c, err)
+		return false
+	}
+	return true
+}
+
+// bindError writes the 400 for a binding failure. Validation and type errors list
+// each offending field and the rule it broke; anything else is a malformed body.
+func bindError(c *gin.Context, err error) {
+	var details []models.FieldError
+
+	var verrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &verrs):
+		for _, fe := range verrs {
+			details = append(details, models.FieldError{
+				Field: fe.Field(),
+				Rule:  fe.Tag(),
+				Param: fe.Param(),
+			})
+		}
+	case errors.As(err, &typeErr):
+		details = append(details, models.FieldError{
+			Field: typeErr.Field,
+			Rule:  "type",
+			Param: typeErr.Type.String(),
+		})
+	default:
+		c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Request body must be valid JSON"))
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, models.ValidationFailedResponse{
+		ErrorResponse: errorResponse(c, "validation_failed", "One or more fields are invalid"),
+		Details:       details,
+	})
+}
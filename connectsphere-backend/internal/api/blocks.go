@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Block handlers
+
+func (s *Server) blockUser(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	blockedID := uuidParam(c, "id")
+
+	if userID == blockedID {
+		c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Cannot block yourself"))
+		return
+	}
+
+	if _, err := s.db.GetUserByID(c.Request.Context(), blockedID); err != nil {
+		c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
+		return
+	}
+
+	if err := s.db.BlockUser(c.Request.Context(), userID, blockedID); err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to block user"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "User blocked successfully",
+	})
+}
+
+func (s *Server) unblockUser(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	blockedID := uuidParam(c, "id")
+
+	if err := s.db.UnblockUser(c.Request.Context(), userID, blockedID); err != nil {
+		c.JSON(http.StatusNotFound, errorResponse(c, "block_not_found", "User is not blocked"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "User unblocked successfully",
+	})
+}
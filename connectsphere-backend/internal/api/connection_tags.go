@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"connectsphere-backend/internal/database"
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Connection tag handlers. Tags are private labels (e.g. "close") a user puts on
+// their own connections; the tagged user never sees them.
+
+func (s *Server) listConnectionTags(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	tags, err := s.db.ListConnectionTags(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to list connection tags"))
+		return
+	}
+
+	respondList(c, models.NewListResponse(tags))
+}
+
+func (s *Server) addConnectionTag(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	otherID := uuidParam(c, "user_id")
+	tag := models.NormalizeTag(c.Param("tag"))
+	if err := models.ValidateTag(tag); err != nil {
+		s.validationError(c, err)
+		return
+	}
+
+	err := s.db.AddConnectionTag(c.Request.Context(), userID, otherID, tag, models.MaxConnectionTagsPerConnection)
+	switch {
+	case errors.Is(err, database.ErrNotConnected):
+		c.JSON(http.StatusNotFound, errorResponse(c, "not_connected", "You are not connected with this user"))
+		return
+	case errors.Is(err, database.ErrTagLimitReached):
+		c.JSON(http.StatusConflict, errorResponse(c, "tag_limit_reached",
+			fmt.Sprintf("A connection can have at most %d tags", models.MaxConnectionTagsPerConnection)))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to add connection tag"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Connection tagged successfully",
+	})
+}
+
+// setConnectionTags replaces all of a connection's tags at once, for clients that
+// edit them as a multi-select rather than one at a time
+func (s *Server) setConnectionTags(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	otherID := uuidParam(c, "friend_id")
+
+	var req models.SetConnectionTagsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tags := make([]string, 0, len(req.Tags))
+	seen := make(map[string]bool, len(req.Tags))
+	for _, tag := range req.Tags {
+		tag = models.NormalizeTag(tag)
+		if err := models.ValidateTag(tag); err != nil {
+			s.validationError(c, err)
+			return
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > models.MaxConnectionTagsPerConnection {
+		c.JSON(http.StatusConflict, errorResponse(c, "tag_limit_reached",
+			fmt.Sprintf("A connection can have at most %d tags", models.MaxConnectionTagsPerConnection)))
+		return
+	}
+
+	tags, err := s.db.SetConnectionTags(c.Request.Context(), userID, otherID, tags)
+	if errors.Is(err, database.ErrNotConnected) {
+		c.JSON(http.StatusNotFound, errorResponse(c, "not_connected", "You are not connected with this user"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to set connection tags"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Connection tags updated successfully",
+		Data:    models.ConnectionTags{UserID: otherID, Tags: tags},
+	})
+}
+
+func (s *Server) removeConnectionTag(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	otherID := uuidParam(c, "user_id")
+	tag := models.NormalizeTag(c.Param("tag"))
+
+	err := s.db.RemoveConnectionTag(c.Request.Context(), userID, otherID, tag)
+	if errors.Is(err, database.ErrTagNotFound) {
+		c.JSON(http.StatusNotFound, errorResponse(c, "tag_not_found", "Connection does not have this tag"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to remove connection tag"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Connection tag removed successfully",
+	})
+}
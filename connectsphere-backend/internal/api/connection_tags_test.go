@@ -0,0 +1,156 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestAddConnectionTag(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob, carol := ts.newUser("alice"), ts.newUser("bob"), ts.newUser("carol")
+	ts.store.addConnection(alice.ID, bob.ID, models.StatusAccepted)
+	ts.store.addConnection(alice.ID, carol.ID, models.StatusPending)
+	token := ts.tokenFor(t, alice)
+	tagPath := func(user *models.User, tag string) string {
+		return fmt.Sprintf("/api/v1/connections/tags/%s/%s", user.ID, tag)
+	}
+
+	expectStatus(t, ts.do(t, http.MethodPut, tagPath(bob, "Close"), token, nil), http.StatusOK)
+	// Adding a tag the connection already has is a no-op
+	expectStatus(t, ts.do(t, http.MethodPut, tagPath(bob, "close"), token, nil), http.StatusOK)
+	if got := ts.store.tags[[2]uuid.UUID{alice.ID, bob.ID}]; !reflect.DeepEqual(got, []string{"close"}) {
+		t.Fatalf("tags = %v, want [close]", got)
+	}
+
+	// Only accepted connections can be tagged
+	expectError(t, ts.do(t, http.MethodPut, tagPath(carol, "close"), token, nil), http.StatusNotFound, "not_connected")
+
+	expectError(t, ts.do(t, http.MethodPut, tagPath(bob, "no%20spaces"), token, nil), http.StatusBadRequest, "invalid_tag")
+
+	// The per-connection cap counts the tag already added
+	for i := 1; i < models.MaxConnectionTagsPerConnection; i++ {
+		expectStatus(t, ts.do(t, http.MethodPut, tagPath(bob, fmt.Sprintf("tag%d", i)), token, nil), http.StatusOK)
+	}
+	expectError(t, ts.do(t, http.MethodPut, tagPath(bob, "one-too-many"), token, nil), http.StatusConflict, "tag_limit_reached")
+	// An existing tag is still accepted at the cap
+	expectStatus(t, ts.do(t, http.MethodPut, tagPath(bob, "close"), token, nil), http.StatusOK)
+
+	// Tags are private: bob sees none on his side of the connection
+	rec := ts.do(t, http.MethodGet, "/api/v1/connections/tags", ts.tokenFor(t, bob), nil)
+	expectStatus(t, rec, http.StatusOK)
+	if list := decode[models.ListResponse[models.ConnectionTagCount]](t, rec); len(list.Data) != 0 {
+		t.Fatalf("bob's tags = %+v, want none", list.Data)
+	}
+}
+
+func TestRemoveConnectionTag(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob := ts.newUser("alice"), ts.newUser("bob")
+	ts.store.addConnection(alice.ID, bob.ID, models.StatusAccepted)
+	token := ts.tokenFor(t, alice)
+	path := fmt.Sprintf("/api/v1/connections/tags/%s/close", bob.ID)
+
+	expectStatus(t, ts.do(t, http.MethodPut, path, token, nil), http.StatusOK)
+	expectStatus(t, ts.do(t, http.MethodDelete, path, token, nil), http.StatusOK)
+	expectError(t, ts.do(t, http.MethodDelete, path, token, nil), http.StatusNotFound, "tag_not_found")
+}
+
+func TestGetConnectionsTagFilter(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob, carol := ts.newUser("alice"), ts.newUser("bob"), ts.newUser("carol")
+	ts.store.addConnection(alice.ID, bob.ID, models.StatusAccepted)
+	ts.store.addConnection(carol.ID, alice.ID, models.StatusAccepted)
+	token := ts.tokenFor(t, alice)
+	expectStatus(t, ts.do(t, http.MethodPut, fmt.Sprintf("/api/v1/connections/tags/%s/close", bob.ID), token, nil), http.StatusOK)
+
+	connectedWith := func(path string) []string {
+		t.Helper()
+		rec := ts.do(t, http.MethodGet, path, token, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var names []string
+		for _, connection := range decode[models.ListResponse[models.ConnectionWithUser]](t, rec).Data {
+			names = append(names, connection.User.Username)
+		}
+		return names
+	}
+
+	if got := connectedWith("/api/v1/connections?tag=close"); !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Fatalf("?tag=close = %v, want [bob]", got)
+	}
+	// The filter is normalized like the tag itself
+	if got := connectedWith("/api/v1/connections?tag=CLOSE"); !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Fatalf("?tag=CLOSE = %v, want [bob]", got)
+	}
+	if got := connectedWith("/api/v1/connections?tag=work"); len(got) != 0 {
+		t.Fatalf("?tag=work = %v, want none", got)
+	}
+	if got := connectedWith("/api/v1/connections"); len(got) != 2 {
+		t.Fatalf("unfiltered = %v, want both connections", got)
+	}
+	expectError(t, ts.do(t, http.MethodGet, "/api/v1/connections?tag=no%20spaces", token, nil), http.StatusBadRequest, "invalid_tag")
+}
+
+func TestConnectionTagsDroppedWhenConnectionEnds(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob := ts.newUser("alice"), ts.newUser("bob")
+	ts.store.addConnection(alice.ID, bob.ID, models.StatusAccepted)
+	aliceToken, bobToken := ts.tokenFor(t, alice), ts.tokenFor(t, bob)
+	expectStatus(t, ts.do(t, http.MethodPut, fmt.Sprintf("/api/v1/connections/tags/%s/close", bob.ID), aliceToken, nil), http.StatusOK)
+	expectStatus(t, ts.do(t, http.MethodPut, fmt.Sprintf("/api/v1/connections/tags/%s/family", alice.ID), bobToken, nil), http.StatusOK)
+
+	expectStatus(t, ts.do(t, http.MethodDelete, "/api/v1/connections/remove-friend/"+bob.ID.String(), aliceToken, nil), http.StatusOK)
+
+	// Neither side's tags survive, so they don't come back if the two reconnect
+	for _, token := range []string{aliceToken, bobToken} {
+		rec := ts.do(t, http.MethodGet, "/api/v1/connections/tags", token, nil)
+		expectStatus(t, rec, http.StatusOK)
+		if list := decode[models.ListResponse[models.ConnectionTagCount]](t, rec); len(list.Data) != 0 {
+			t.Fatalf("tags after removing the connection = %+v, want none", list.Data)
+		}
+	}
+	ts.store.addConnection(bob.ID, alice.ID, models.StatusAccepted)
+	rec := ts.do(t, http.MethodGet, "/api/v1/connections", aliceToken, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if list := decode[models.ListResponse[models.ConnectionWithUser]](t, rec); len(list.Data) != 1 || len(list.Data[0].Tags) != 0 {
+		t.Fatalf("connections after reconnecting = %+v, want one without tags", list.Data)
+	}
+}
+
+func TestSetConnectionTags(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob, carol := ts.newUser("alice"), ts.newUser("bob"), ts.newUser("carol")
+	ts.store.addConnection(alice.ID, bob.ID, models.StatusAccepted)
+	token := ts.tokenFor(t, alice)
+	path := "/api/v1/connections/" + bob.ID.String() + "/tags"
+
+	rec := ts.do(t, http.MethodPut, path, token, map[string][]string{"tags": {"Work", "close", "work"}})
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[struct{ Data models.ConnectionTags }](t, rec).Data.Tags; !reflect.DeepEqual(got, []string{"close", "work"}) {
+		t.Fatalf("tags = %v, want [close work]", got)
+	}
+
+	// Replacing with no tags clears them and answers an empty list, not null
+	rec = ts.do(t, http.MethodPut, path, token, map[string][]string{"tags": {}})
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), `"tags":[]`) {
+		t.Fatalf("body %s, want \"tags\":[]", rec.Body)
+	}
+	if got := ts.store.tags[[2]uuid.UUID{alice.ID, bob.ID}]; len(got) != 0 {
+		t.Fatalf("stored tags = %v, want none", got)
+	}
+
+	tooMany := make([]string, models.MaxConnectionTagsPerConnection+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+	expectError(t, ts.do(t, http.MethodPut, path, token, map[string][]string{"tags": tooMany}), http.StatusConflict, "tag_limit_reached")
+	expectError(t, ts.do(t, http.MethodPut, "/api/v1/connections/"+carol.ID.String()+"/tags", token, map[string][]string{"tags": {"close"}}),
+		http.StatusNotFound, "not_connected")
+}
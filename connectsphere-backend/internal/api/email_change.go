@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"connectsphere-backend/internal/auth"
+	"connectsphere-backend/internal/database"
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// verifyEmailChangePath confirms an email change from the emailed link, so it is a GET that writes
+const verifyEmailChangePath = "/api/v1/auth/verify-email-change"
+
+// Email change handlers
+
+func (s *Server) requestEmailChange(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.ChangeEmailRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	req.NewEmail = auth.NormalizeEmail(req.NewEmail, s.cfg.CanonicalizeGmail)
+	if err := models.ValidateEmail(req.NewEmail); err != nil {
+		s.validationError(c, err)
+		return
+	}
+
+	user, err := s.db.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
+		return
+	}
+
+	if !auth.CheckPassword(user.HashedPassword, req.CurrentPassword) {
+		c.JSON(http.StatusUnauthorized, errorResponse(c, "invalid_credentials", "Current password is incorrect"))
+		return
+	}
+
+	taken, err := s.db.IsEmailTaken(c.Request.Context(), req.NewEmail, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to check email"))
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, errorResponse(c, "email_taken", "Email is already in use"))
+		return
+	}
+
+	token, tokenHash, err := auth.GenerateVerificationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to generate verification token"))
+		return
+	}
+
+	expiresAt := time.Now().Add(s.cfg.EmailChangeTokenExpiry)
+	if err := s.db.CreateEmailChangeRequest(c.Request.Context(), userID, req.NewEmail, tokenHash, expiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to request email change"))
+		return
+	}
+
+	// There is no mailer yet; expose the confirmation link in development logs only
+	if gin.IsDebugging() {
+		log.Printf("Email change confirmation for %s: %s?token=%s",
+			req.NewEmail, verifyEmailChangePath, url.QueryEscape(token))
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse{
+		Message: "Check your new email address to confirm the change",
+	})
+}
+
+func (s *Server) verifyEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Query parameter 'token' is required"))
+		return
+	}
+
+	user, err := s.db.ConfirmEmailChange(c.Request.Context(), auth.HashToken(token))
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidToken):
+			c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_token", "Verification link is invalid or has expired"))
+		case errors.Is(err, database.ErrEmailTaken):
+			c.JSON(http.StatusConflict, errorResponse(c, "email_taken", "Email is already in use"))
+		default:
+			c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to confirm email change"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Email changed successfully",
+		Data:    user.ToAuth(),
+	})
+}
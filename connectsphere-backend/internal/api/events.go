@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"connectsphere-backend/internal/events"
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// sseHeartbeatInterval keeps idle streams from being closed by proxies
+const sseHeartbeatInterval = 25 * time.Second
+
+// notify stores a notification for the user and delivers it as a live event to
+// all of their connected clients. The event carries the notification_id so
+// clients can mark it read. It is also queued for the outbound webhook.
+func (s *Server) notify(ctx context.Context, userID uuid.UUID, eventType string, payload gin.H) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode %s notification: %v", eventType, err)
+		return
+	}
+
+	notification, err := s.db.CreateNotification(ctx, userID, eventType, data)
+	if err != nil {
+		log.Printf("Failed to store %s notification for %s: %v", eventType, userID, err)
+	} else {
+		payload["notification_id"] = notification.ID
+	}
+
+	if err := s.hub.Publish(userID, eventType, payload); err != nil {
+		log.Printf("Failed to publish %s event to %s: %v", eventType, userID, err)
+	}
+
+	webhookData := gin.H{"recipient_id": userID}
+	for key, value := range payload {
+		webhookData[key] = value
+	}
+	s.webhooks.Send(eventType, webhookData)
+}
+
+// notifyConnectionAccepted tells the original requester that accepterID accepted their request
+func (s *Server) notifyConnectionAccepted(c *gin.Context, requesterID, accepterID uuid.UUID) {
+	accepter, err := s.db.GetUserByID(c.Request.Context(), accepterID)
+	if err != nil {
+		log.Printf("Failed to load %s for connection_accepted event: %v", accepterID, err)
+		return
+	}
+
+	s.notify(c.Request.Context(), requesterID, "connection_accepted", gin.H{"user": accepter.ToPublic()})
+}
+
+// broadcastStatus sends the user's new status message to each of their connections'
+// live clients. Status changes are transient, so no notifications are stored.
+func (s *Server) broadcastStatus(c *gin.Context, user *models.User) {
+	connections, err := s.db.GetUserConnections(c.Request.Context(), user.ID, "")
+	if err != nil {
+		log.Printf("Failed to load connections of %s for status_updated event: %v", user.ID, err)
+		return
+	}
+
+	auth := user.ToAuth() // the status fields with expiry already applied
+	payload := gin.H{
+		"user_id":           user.ID,
+		"status_message":    auth.StatusMessage,
+		"status_expires_at": auth.StatusExpiresAt,
+	}
+	for _, connection := range connections {
+		if err := s.hub.Publish(connection.User.ID, "status_updated", payload); err != nil {
+			log.Printf("Failed to publish status_updated event to %s: %v", connection.User.ID, err)
+		}
+	}
+}
+
+// tokenFromQuery lets clients that can't set headers (e.g. browser EventSource)
+// pass the JWT as ?access_token=, for the routes it is applied to
+func (s *Server) tokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
+// streamEvents streams the user's hub events as server-sent events, the fallback
+// for clients whose network blocks WebSocket. Reconnecting clients send
+// Last-Event-ID to receive the events they missed.
+func (s *Server) streamEvents(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	lastEventID, _ := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	if lastEventID == 0 {
+		lastEventID, _ = strconv.ParseUint(c.Query("last_event_id"), 10, 64)
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Streaming is not supported"))
+		return
+	}
+
+	sub, err := s.hub.Subscribe(userID, lastEventID)
+	if errors.Is(err, events.ErrTooManySubscriptions) {
+		c.JSON(http.StatusTooManyRequests, errorResponse(c, "too_many_streams",
+			fmt.Sprintf("At most %d event streams may be open at once", s.cfg.MaxEventStreamsPerUser)))
+		return
+	}
+	defer s.hub.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable nginx response buffering
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Dropped for falling behind; the client reconnects with Last-Event-ID
+				return
+			}
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
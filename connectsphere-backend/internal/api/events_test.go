@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"connectsphere-backend/internal/events"
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// receivedTypes returns the types of the events queued on sub without waiting
+func receivedTypes(sub *events.Subscription) []string {
+	var types []string
+	for {
+		select {
+		case event := <-sub.Events():
+			types = append(types, event.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestConnectionAcceptedNotifiesRequester(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(requesterID uuid.UUID) string
+	}{
+		{"accept endpoint", func(id uuid.UUID) string { return "/api/v1/connections/accept-request/" + id.String() }},
+		{"sending a reverse request", func(id uuid.UUID) string { return "/api/v1/connections/send-request/" + id.String() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			requester, accepter := ts.newUser("requester"), ts.newUser("accepter")
+			ts.store.addConnection(requester.ID, accepter.ID, models.StatusPending)
+
+			requesterSub, err := ts.hub.Subscribe(requester.ID, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			accepterSub, err := ts.hub.Subscribe(accepter.ID, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectStatus(t, ts.do(t, http.MethodPost, tt.path(requester.ID), ts.tokenFor(t, accepter), nil), http.StatusOK)
+
+			event := <-requesterSub.Events()
+			if event.Type != "connection_accepted" {
+				t.Fatalf("requester got %q, want connection_accepted", event.Type)
+			}
+			var payload struct {
+				User models.UserPublic `json:"user"`
+			}
+			if err := json.Unmarshal(event.Data, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.User.ID != accepter.ID {
+				t.Fatalf("event names user %s, want the accepter %s", payload.User.ID, accepter.ID)
+			}
+
+			if got := receivedTypes(accepterSub); len(got) != 0 {
+				t.Fatalf("accepter got events %v, want none", got)
+			}
+			if got := ts.store.notificationsFor(accepter.ID); len(got) != 0 {
+				t.Fatalf("accepter has notifications %v, want none", got)
+			}
+			if got := ts.store.notificationsFor(requester.ID); len(got) != 1 || got[0] != "connection_accepted" {
+				t.Fatalf("requester has notifications %v, want [connection_accepted]", got)
+			}
+		})
+	}
+}
+
+func TestEventStreamLimit(t *testing.T) {
+	const limit = 3
+	ts := newTestServer(t, map[string]string{"MAX_EVENT_STREAMS_PER_USER": strconv.Itoa(limit)})
+	token := ts.tokenFor(t, ts.newUser("alice"))
+	server := httptest.NewServer(ts.router)
+	defer server.Close()
+
+	open := func(ctx context.Context) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/events?access_token="+token, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	cancels := make([]context.CancelFunc, 0, limit)
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	for i := 0; i < limit; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels = append(cancels, cancel)
+		if resp := open(ctx); resp.StatusCode != http.StatusOK {
+			t.Fatalf("stream %d: status %d", i+1, resp.StatusCode)
+		}
+	}
+
+	resp := open(context.Background())
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("stream %d: status %d, want 429", limit+1, resp.StatusCode)
+	}
+	var body models.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error != "too_many_streams" {
+		t.Fatalf("stream %d: body %+v, %v; want too_many_streams", limit+1, body, err)
+	}
+
+	// Closing a stream frees its slot once the server notices the disconnect
+	cancels[0]()
+	deadline := time.Now().Add(5 * time.Second)
+	for ts.hub.Stats().Subscribers >= limit {
+		if time.Now().After(deadline) {
+			t.Fatal("closed stream was never unsubscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancels = append(cancels, cancel)
+	if resp := open(ctx); resp.StatusCode != http.StatusOK {
+		t.Fatalf("stream after closing one: status %d", resp.StatusCode)
+	}
+}
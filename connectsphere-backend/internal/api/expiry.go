@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExpireConnectionRequests removes pending requests older than
+// CONNECTION_REQUEST_EXPIRY every interval until ctx is cancelled, notifying each
+// sender that their request lapsed. It does nothing if expiry is disabled, and
+// skips runs while read-only mode is on; the next run catches up.
+func (s *Server) ExpireConnectionRequests(ctx context.Context, interval time.Duration) {
+	if s.cfg.ConnectionRequestExpiry <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.expireConnectionRequests(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) expireConnectionRequests(ctx context.Context) {
+	if s.readOnly.Load() {
+		return
+	}
+
+	expired, err := s.db.ExpirePendingConnections(ctx, time.Now().Add(-s.cfg.ConnectionRequestExpiry))
+	if err != nil {
+		log.Printf("Failed to expire connection requests: %v", err)
+		return
+	}
+	if len(expired) > 0 {
+		log.Printf("Expired %d pending connection requests", len(expired))
+	}
+
+	for _, connection := range expired {
+		s.notify(ctx, connection.RequesterID, "connection_request_expired", gin.H{
+			"connection_id": connection.ID,
+			"addressee_id":  connection.AddresseeID,
+		})
+	}
+}
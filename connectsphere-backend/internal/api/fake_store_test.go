@@ -0,0 +1,1243 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"connectsphere-backend/internal/database"
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// fakeStore is an in-memory Store for handler tests. It mirrors the behaviour of
+// *database.DB closely enough for handler logic (status codes, validation, auth
+// and visibility checks) to be tested without Postgres; SQL-level behaviour such
+// as search ranking is covered by the integration tests in internal/database.
+type fakeStore struct {
+	mu sync.Mutex
+
+	users         map[uuid.UUID]*models.User
+	connections   []*models.UserConnection
+	requestLog    []fakeLoggedRequest
+	blocks        map[[2]uuid.UUID]bool // blocker, blocked
+	sessions      map[uuid.UUID]*fakeSession
+	notifications []*models.Notification
+	identities    map[string]uuid.UUID // issuer + " " + subject
+	invites       map[string]*models.InviteCode
+	tags          map[[2]uuid.UUID][]string // user, connection user
+	emailChanges  map[string]fakeEmailChange
+	nameHistory   map[uuid.UUID][]models.DisplayNameChange
+	events        []models.ConnectionEvent
+
+	// uniqueDisplayNames mimics the index created for UNIQUE_DISPLAY_NAMES
+	uniqueDisplayNames bool
+	// healthErr is returned by HealthCheck
+	healthErr error
+}
+
+type fakeLoggedRequest struct {
+	requesterID uuid.UUID
+	at          time.Time
+}
+
+type fakeSession struct {
+	models.Session
+	revoked bool
+}
+
+type fakeEmailChange struct {
+	userID    uuid.UUID
+	newEmail  string
+	expiresAt time.Time
+}
+
+var _ Store = (*fakeStore)(nil)
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:        make(map[uuid.UUID]*models.User),
+		blocks:       make(map[[2]uuid.UUID]bool),
+		sessions:     make(map[uuid.UUID]*fakeSession),
+		identities:   make(map[string]uuid.UUID),
+		invites:      make(map[string]*models.InviteCode),
+		tags:         make(map[[2]uuid.UUID][]string),
+		emailChanges: make(map[string]fakeEmailChange),
+		nameHistory:  make(map[uuid.UUID][]models.DisplayNameChange),
+	}
+}
+
+// addUser stores a user with the database defaults for any unset fields and returns it
+func (f *fakeStore) addUser(user models.User) *models.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.insertUser(&user); err != nil {
+		panic(err)
+	}
+	return f.users[user.ID]
+}
+
+// insertUser applies the users table defaults and constraints; callers hold f.mu
+func (f *fakeStore) insertUser(user *models.User) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	for _, other := range f.users {
+		switch {
+		case strings.EqualFold(other.Username, user.Username):
+			return database.ErrUsernameTaken
+		case strings.EqualFold(other.Email, user.Email):
+			return fmt.Errorf("failed to create user: duplicate email")
+		case f.uniqueDisplayNames && strings.EqualFold(other.DisplayName, user.DisplayName):
+			return database.ErrDisplayNameTaken
+		}
+	}
+
+	if user.ProfileVisibility == "" {
+		user.ProfileVisibility = models.VisibilityPublic
+	}
+	if user.ConnectionRequestPolicy == "" {
+		user.ConnectionRequestPolicy = models.RequestPolicyEveryone
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+		user.DiscoverableByEmail = true
+		user.ShowConnections = true
+	}
+	user.UpdatedAt = user.CreatedAt
+
+	stored := *user
+	f.users[user.ID] = &stored
+	return nil
+}
+
+// addConnection stores a connection row between two users and returns it
+func (f *fakeStore) addConnection(requesterID, addresseeID uuid.UUID, status models.ConnectionStatus) *models.UserConnection {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	connection := &models.UserConnection{
+		ID:          uuid.New(),
+		RequesterID: requesterID,
+		AddresseeID: addresseeID,
+		Status:      status,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	f.connections = append(f.connections, connection)
+	return connection
+}
+
+// addBlock records that blockerID blocked blockedID
+func (f *fakeStore) addBlock(blockerID, blockedID uuid.UUID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.blocks[[2]uuid.UUID{blockerID, blockedID}] = true
+}
+
+// notificationsFor returns the types of the notifications stored for userID, oldest first
+func (f *fakeStore) notificationsFor(userID uuid.UUID) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var types []string
+	for _, n := range f.notifications {
+		if n.UserID == userID {
+			types = append(types, n.Type)
+		}
+	}
+	return types
+}
+
+func (f *fakeStore) HealthCheck(ctx context.Context) error {
+	return f.healthErr
+}
+
+// Users
+
+func (f *fakeStore) CreateUser(ctx context.Context, user *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.insertUser(user); err != nil {
+		return err
+	}
+	*user = *f.users[user.ID]
+	return nil
+}
+
+// findUser returns a copy of the first user matching match; callers hold f.mu
+func (f *fakeStore) findUser(match func(*models.User) bool) (*models.User, error) {
+	for _, user := range f.users {
+		if match(user) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (f *fakeStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.findUser(func(u *models.User) bool { return strings.EqualFold(u.Email, email) })
+}
+
+func (f *fakeStore) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.findUser(func(u *models.User) bool { return u.ID == id })
+}
+
+func (f *fakeStore) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, []uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	users := make([]*models.User, 0, len(ids))
+	notFound := make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if user, ok := f.users[id]; ok {
+			found := *user
+			users = append(users, &found)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+	return users, notFound, nil
+}
+
+func (f *fakeStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.findUser(func(u *models.User) bool { return strings.EqualFold(u.Username, username) })
+}
+
+func (f *fakeStore) UpdatePasswordHash(ctx context.Context, id uuid.UUID, hashedPassword string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, ok := f.users[id]; ok {
+		user.HashedPassword = hashedPassword
+	}
+	return nil
+}
+
+func (f *fakeStore) UpdateUser(ctx context.Context, id uuid.UUID, params database.UpdateUserParams) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	if (params.ExpectedUpdatedAt != nil && !user.UpdatedAt.Equal(*params.ExpectedUpdatedAt)) ||
+		(params.UnmodifiedSince != nil && !user.UpdatedAt.Before(params.UnmodifiedSince.Add(time.Second))) {
+		return nil, database.ErrProfileModified
+	}
+
+	if params.DisplayName != nil && *params.DisplayName != user.DisplayName {
+		for _, other := range f.users {
+			if f.uniqueDisplayNames && other.ID != id && strings.EqualFold(other.DisplayName, *params.DisplayName) {
+				return nil, database.ErrDisplayNameTaken
+			}
+		}
+		history := f.nameHistory[id]
+		if len(history) > 0 && params.DisplayNameCooldown > 0 {
+			if retryAt := history[0].ChangedAt.Add(params.DisplayNameCooldown); time.Now().Before(retryAt) {
+				return nil, &database.DisplayNameCooldownError{RetryAt: retryAt}
+			}
+		}
+		f.nameHistory[id] = append([]models.DisplayNameChange{{
+			ID:             uuid.New(),
+			OldDisplayName: user.DisplayName,
+			NewDisplayName: *params.DisplayName,
+			ChangedAt:      time.Now(),
+		}}, history...)
+		user.DisplayName = *params.DisplayName
+	}
+	if params.ProfileVisibility != nil {
+		user.ProfileVisibility = *params.ProfileVisibility
+	}
+	if params.DiscoverableByEmail != nil {
+		user.DiscoverableByEmail = *params.DiscoverableByEmail
+	}
+	if params.ConnectionRequestPolicy != nil {
+		user.ConnectionRequestPolicy = *params.ConnectionRequestPolicy
+	}
+	if params.ShowConnections != nil {
+		user.ShowConnections = *params.ShowConnections
+	}
+	if params.StatusMessage != nil {
+		user.StatusMessage = nil
+		if *params.StatusMessage != "" {
+			status := *params.StatusMessage
+			user.StatusMessage = &status
+		}
+		user.StatusExpiresAt = params.StatusExpiresAt
+	}
+	user.UpdatedAt = time.Now()
+
+	updated := *user
+	return &updated, nil
+}
+
+// connected reports whether two users have an accepted connection; callers hold f.mu
+func (f *fakeStore) connected(userID, otherID uuid.UUID) bool {
+	connection := f.connectionBetween(userID, otherID)
+	return connection != nil && connection.Status == models.StatusAccepted
+}
+
+// blocked reports whether either user blocked the other; callers hold f.mu
+func (f *fakeStore) blocked(userID, otherID uuid.UUID) bool {
+	return f.blocks[[2]uuid.UUID{userID, otherID}] || f.blocks[[2]uuid.UUID{otherID, userID}]
+}
+
+// visibleTo mirrors the visibleToViewer and notBlocked conditions; callers hold f.mu
+func (f *fakeStore) visibleTo(user *models.User, viewerID uuid.UUID) bool {
+	if f.blocked(viewerID, user.ID) {
+		return false
+	}
+	return user.ProfileVisibility != models.VisibilityPrivate || user.ID == viewerID || f.connected(viewerID, user.ID)
+}
+
+func (f *fakeStore) SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit, offset int) ([]*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lowerQuery := strings.ToLower(query)
+	rank := func(u *models.User) int {
+		username, displayName := strings.ToLower(u.Username), strings.ToLower(u.DisplayName)
+		switch {
+		case username == lowerQuery || displayName == lowerQuery:
+			return 1
+		case strings.HasPrefix(username, lowerQuery) || strings.HasPrefix(displayName, lowerQuery):
+			return 2
+		case strings.Contains(username, lowerQuery) || strings.Contains(displayName, lowerQuery):
+			return 3
+		}
+		return 4
+	}
+
+	var matches []*models.User
+	for _, user := range f.users {
+		if !f.visibleTo(user, viewerID) {
+			continue
+		}
+		matchesAll := true
+		for _, token := range strings.Fields(lowerQuery) {
+			if !strings.Contains(strings.ToLower(user.Username), token) && !strings.Contains(strings.ToLower(user.DisplayName), token) {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			found := *user
+			matches = append(matches, &found)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if ri, rj := rank(matches[i]), rank(matches[j]); ri != rj {
+			return ri < rj
+		}
+		if matches[i].Username != matches[j].Username {
+			return matches[i].Username < matches[j].Username
+		}
+		return matches[i].ID.String() < matches[j].ID.String()
+	})
+
+	return page(matches, limit, offset), nil
+}
+
+func (f *fakeStore) FindUserByEmail(ctx context.Context, viewerID uuid.UUID, email string) ([]*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	users := make([]*models.User, 0, 1)
+	for _, user := range f.users {
+		if strings.EqualFold(user.Email, email) && user.DiscoverableByEmail && f.visibleTo(user, viewerID) {
+			found := *user
+			users = append(users, &found)
+		}
+	}
+	return users, nil
+}
+
+// Display names
+
+func (f *fakeStore) IsDisplayNameTaken(ctx context.Context, displayName string, excludeUserID uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, user := range f.users {
+		if user.ID != excludeUserID && strings.EqualFold(user.DisplayName, displayName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// External identities
+
+func (f *fakeStore) GetOrCreateExternalUser(ctx context.Context, issuer, subject string, emailVerified bool, newUser *models.User, registrationMode, inviteCode string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := issuer + " " + subject
+	if id, ok := f.identities[key]; ok {
+		user := *f.users[id]
+		return &user, nil
+	}
+
+	existing, err := f.findUser(func(u *models.User) bool { return strings.EqualFold(u.Email, newUser.Email) })
+	switch {
+	case err == nil && !emailVerified:
+		return nil, database.ErrEmailTaken
+	case err == nil:
+		f.identities[key] = existing.ID
+		return existing, nil
+	}
+
+	if registrationMode == "closed" {
+		return nil, database.ErrRegistrationClosed
+	}
+	var invite *models.InviteCode
+	if registrationMode == "invite" {
+		var ok bool
+		invite, ok = f.invites[inviteCode]
+		if !ok || invite.UsedAt != nil || (invite.ExpiresAt != nil && !invite.ExpiresAt.After(time.Now())) {
+			return nil, database.ErrInvalidInviteCode
+		}
+	}
+
+	user := *newUser
+	if err := f.insertUser(&user); err != nil {
+		return nil, err
+	}
+	if invite != nil {
+		now := time.Now()
+		invite.UsedBy, invite.UsedAt = &user.ID, &now
+	}
+	f.identities[key] = user.ID
+	created := *f.users[user.ID]
+	return &created, nil
+}
+
+// Email changes
+
+func (f *fakeStore) IsEmailTaken(ctx context.Context, email string, excludeUserID uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, user := range f.users {
+		if user.ID != excludeUserID && strings.EqualFold(user.Email, email) {
+			return true, nil
+		}
+	}
+	for _, change := range f.emailChanges {
+		if change.userID != excludeUserID && strings.EqualFold(change.newEmail, email) && change.expiresAt.After(time.Now()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStore) CreateEmailChangeRequest(ctx context.Context, userID uuid.UUID, newEmail, tokenHash string, expiresAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for hash, change := range f.emailChanges {
+		if change.userID == userID {
+			delete(f.emailChanges, hash)
+		}
+	}
+	f.emailChanges[tokenHash] = fakeEmailChange{userID: userID, newEmail: newEmail, expiresAt: expiresAt}
+	return nil
+}
+
+func (f *fakeStore) ConfirmEmailChange(ctx context.Context, tokenHash string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	change, ok := f.emailChanges[tokenHash]
+	if !ok || !change.expiresAt.After(time.Now()) {
+		return nil, database.ErrInvalidToken
+	}
+	delete(f.emailChanges, tokenHash)
+
+	for _, user := range f.users {
+		if user.ID != change.userID && strings.EqualFold(user.Email, change.newEmail) {
+			return nil, database.ErrEmailTaken
+		}
+	}
+
+	user := f.users[change.userID]
+	user.Email = change.newEmail
+	user.UpdatedAt = time.Now()
+	updated := *user
+	return &updated, nil
+}
+
+// Connections
+
+// connectionBetween returns the connection row between two users in either direction; callers hold f.mu
+func (f *fakeStore) connectionBetween(userID, otherID uuid.UUID) *models.UserConnection {
+	for _, connection := range f.connections {
+		if (connection.RequesterID == userID && connection.AddresseeID == otherID) ||
+			(connection.RequesterID == otherID && connection.AddresseeID == userID) {
+			return connection
+		}
+	}
+	return nil
+}
+
+// removeConnectionRow deletes a connection and both users' tags on it; callers hold f.mu
+func (f *fakeStore) removeConnectionRow(target *models.UserConnection) {
+	for i, connection := range f.connections {
+		if connection == target {
+			f.connections = append(f.connections[:i], f.connections[i+1:]...)
+			break
+		}
+	}
+	delete(f.tags, [2]uuid.UUID{target.RequesterID, target.AddresseeID})
+	delete(f.tags, [2]uuid.UUID{target.AddresseeID, target.RequesterID})
+}
+
+// recordEvent appends to the connection event log; callers hold f.mu
+func (f *fakeStore) recordEvent(requesterID, addresseeID, actorID uuid.UUID, event string) {
+	f.events = append(f.events, models.ConnectionEvent{
+		ID:          uuid.New(),
+		RequesterID: requesterID,
+		AddresseeID: addresseeID,
+		Event:       event,
+		ActorID:     actorID,
+		CreatedAt:   time.Now(),
+	})
+}
+
+func (f *fakeStore) CreateConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.UserConnection, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if existing := f.connectionBetween(requesterID, addresseeID); existing != nil {
+		if existing.Status != models.StatusDeclined {
+			return nil, fmt.Errorf("failed to create connection: duplicate key")
+		}
+		f.removeConnectionRow(existing)
+	}
+
+	now := time.Now()
+	connection := &models.UserConnection{
+		ID:          uuid.New(),
+		RequesterID: requesterID,
+		AddresseeID: addresseeID,
+		Status:      models.StatusPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	f.connections = append(f.connections, connection)
+	f.requestLog = append(f.requestLog, fakeLoggedRequest{requesterID: requesterID, at: now})
+	f.recordEvent(requesterID, addresseeID, requesterID, models.ConnectionEventCreated)
+
+	created := *connection
+	return &created, nil
+}
+
+func (f *fakeStore) CountConnectionRequestsSince(ctx context.Context, requesterID uuid.UUID, since time.Time) (int, *time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	count := 0
+	var oldest *time.Time
+	for _, entry := range f.requestLog {
+		if entry.requesterID == requesterID && entry.at.After(since) {
+			count++
+			if oldest == nil || entry.at.Before(*oldest) {
+				at := entry.at
+				oldest = &at
+			}
+		}
+	}
+	return count, oldest, nil
+}
+
+// friendsOf returns the IDs of userID's accepted connections; callers hold f.mu
+func (f *fakeStore) friendsOf(userID uuid.UUID) map[uuid.UUID]bool {
+	friends := make(map[uuid.UUID]bool)
+	for _, connection := range f.connections {
+		if connection.Status != models.StatusAccepted {
+			continue
+		}
+		switch userID {
+		case connection.RequesterID:
+			friends[connection.AddresseeID] = true
+		case connection.AddresseeID:
+			friends[connection.RequesterID] = true
+		}
+	}
+	return friends
+}
+
+func (f *fakeStore) CountMutualConnections(ctx context.Context, userID, otherID uuid.UUID) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	theirs := f.friendsOf(otherID)
+	count := 0
+	for id := range f.friendsOf(userID) {
+		if theirs[id] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeStore) GetConnectionByID(ctx context.Context, id uuid.UUID) (*models.UserConnection, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, connection := range f.connections {
+		if connection.ID == id {
+			found := *connection
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("connection not found")
+}
+
+func (f *fakeStore) ConnectedUserIDs(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	friends := f.friendsOf(userID)
+	connected := make(map[uuid.UUID]bool)
+	for _, id := range otherIDs {
+		if friends[id] {
+			connected[id] = true
+		}
+	}
+	return connected, nil
+}
+
+func (f *fakeStore) AreConnected(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.connected(userID, otherID), nil
+}
+
+func (f *fakeStore) AcceptConnection(ctx context.Context, requesterID, addresseeID uuid.UUID, maxConnections int) (*models.UserConnection, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if maxConnections > 0 {
+		for _, userID := range []uuid.UUID{addresseeID, requesterID} {
+			if len(f.friendsOf(userID)) >= maxConnections {
+				return nil, &database.ConnectionLimitError{UserID: userID}
+			}
+		}
+	}
+
+	connection := f.connectionBetween(requesterID, addresseeID)
+	if connection == nil || connection.RequesterID != requesterID || connection.Status != models.StatusPending {
+		return nil, fmt.Errorf("pending connection request not found")
+	}
+	connection.Status = models.StatusAccepted
+	connection.UpdatedAt = time.Now()
+	f.recordEvent(requesterID, addresseeID, addresseeID, models.ConnectionEventAccepted)
+
+	accepted := *connection
+	return &accepted, nil
+}
+
+func (f *fakeStore) DeclineConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.UserConnection, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	connection := f.connectionBetween(requesterID, addresseeID)
+	if connection == nil || connection.RequesterID != requesterID || connection.Status != models.StatusPending {
+		return nil, fmt.Errorf("pending connection request not found")
+	}
+	connection.Status = models.StatusDeclined
+	connection.UpdatedAt = time.Now()
+	f.recordEvent(requesterID, addresseeID, addresseeID, models.ConnectionEventDeclined)
+
+	declined := *connection
+	return &declined, nil
+}
+
+func (f *fakeStore) RemoveConnection(ctx context.Context, userID, friendID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	connection := f.connectionBetween(userID, friendID)
+	if connection == nil || connection.Status != models.StatusAccepted {
+		return fmt.Errorf("friendship not found")
+	}
+	f.removeConnectionRow(connection)
+	f.recordEvent(connection.RequesterID, connection.AddresseeID, userID, models.ConnectionEventRemoved)
+	return nil
+}
+
+func (f *fakeStore) RemoveConnectionByID(ctx context.Context, connectionID, requestingUserID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, connection := range f.connections {
+		if connection.ID != connectionID || connection.Status != models.StatusAccepted {
+			continue
+		}
+		if requestingUserID != connection.RequesterID && requestingUserID != connection.AddresseeID {
+			return database.ErrNotConnectionParticipant
+		}
+		f.removeConnectionRow(connection)
+		f.recordEvent(connection.RequesterID, connection.AddresseeID, requestingUserID, models.ConnectionEventRemoved)
+		return nil
+	}
+	return database.ErrConnectionNotFound
+}
+
+// withUser pairs a connection with the other user's public profile; callers hold f.mu
+func (f *fakeStore) withUser(connection *models.UserConnection, userID uuid.UUID) models.ConnectionWithUser {
+	otherID := connection.RequesterID
+	if otherID == userID {
+		otherID = connection.AddresseeID
+	}
+	other := f.users[otherID]
+
+	view := other.ToPublic()
+	if connection.Status == models.StatusAccepted {
+		view = other.ToConnectionView()
+	}
+	return models.ConnectionWithUser{Connection: *connection, User: view}
+}
+
+func (f *fakeStore) GetConnectionOverview(ctx context.Context, userID uuid.UUID, limit, offset int) (*models.ConnectionOverview, error) {
+	accepted, err := f.GetUserConnections(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	overview := models.NewConnectionOverview(limit, offset)
+	sections := map[*models.ListResponse[models.ConnectionWithUser]][]models.ConnectionWithUser{&overview.Accepted: accepted}
+	for i := len(f.connections) - 1; i >= 0; i-- {
+		connection := f.connections[i]
+		if connection.Status != models.StatusPending {
+			continue
+		}
+		switch userID {
+		case connection.AddresseeID:
+			sections[&overview.Incoming] = append(sections[&overview.Incoming], f.withUser(connection, userID))
+		case connection.RequesterID:
+			sections[&overview.Outgoing] = append(sections[&overview.Outgoing], f.withUser(connection, userID))
+		}
+	}
+	for list, all := range sections {
+		*list.Pagination.Total = len(all)
+		for _, conn := range all {
+			conn.Tags = nil
+			list.Data = append(list.Data, conn)
+		}
+		list.Data = page(list.Data, limit, offset)
+	}
+	return overview, nil
+}
+
+func (f *fakeStore) GetConnectionCounts(ctx context.Context, userID uuid.UUID) (*models.ConnectionCounts, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counts := &models.ConnectionCounts{}
+	for _, connection := range f.connections {
+		switch {
+		case connection.Status == models.StatusAccepted && (connection.RequesterID == userID || connection.AddresseeID == userID):
+			counts.Connections++
+		case connection.Status == models.StatusPending && connection.AddresseeID == userID:
+			counts.PendingIncoming++
+		case connection.Status == models.StatusPending && connection.RequesterID == userID:
+			counts.PendingOutgoing++
+		}
+	}
+	return counts, nil
+}
+
+func (f *fakeStore) ListConnectionEvents(ctx context.Context, userID, otherID uuid.UUID) ([]models.ConnectionEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	events := make([]models.ConnectionEvent, 0)
+	for _, event := range f.events {
+		if (event.RequesterID == userID && event.AddresseeID == otherID) || (event.RequesterID == otherID && event.AddresseeID == userID) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeStore) GetUserConnections(ctx context.Context, userID uuid.UUID, tag string) ([]models.ConnectionWithUser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	connections := make([]models.ConnectionWithUser, 0)
+	for _, connection := range f.connections {
+		if connection.Status != models.StatusAccepted || (connection.RequesterID != userID && connection.AddresseeID != userID) {
+			continue
+		}
+		conn := f.withUser(connection, userID)
+		conn.Tags = append([]string{}, f.tags[[2]uuid.UUID{userID, conn.User.ID}]...)
+		if tag != "" && !contains(conn.Tags, tag) {
+			continue
+		}
+		connections = append(connections, conn)
+	}
+	sort.SliceStable(connections, func(i, j int) bool {
+		return connections[i].User.DisplayName < connections[j].User.DisplayName
+	})
+	return connections, nil
+}
+
+func (f *fakeStore) GetConnectionsOf(ctx context.Context, ownerID, viewerID uuid.UUID, limit, offset int) ([]models.UserLimited, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var users []models.UserLimited
+	for id := range f.friendsOf(ownerID) {
+		if user := f.users[id]; f.visibleTo(user, viewerID) {
+			users = append(users, user.ToLimited())
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Username != users[j].Username {
+			return users[i].Username < users[j].Username
+		}
+		return users[i].ID.String() < users[j].ID.String()
+	})
+	return page(users, limit, offset), len(users), nil
+}
+
+// connectedForTags checks there is an accepted connection to tag; callers hold f.mu
+func (f *fakeStore) connectedForTags(userID, otherID uuid.UUID) error {
+	if !f.connected(userID, otherID) {
+		return database.ErrNotConnected
+	}
+	return nil
+}
+
+func (f *fakeStore) AddConnectionTag(ctx context.Context, userID, otherID uuid.UUID, tag string, maxPerConnection int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.connectedForTags(userID, otherID); err != nil {
+		return err
+	}
+	key := [2]uuid.UUID{userID, otherID}
+	if contains(f.tags[key], tag) {
+		return nil
+	}
+	if len(f.tags[key]) >= maxPerConnection {
+		return database.ErrTagLimitReached
+	}
+	f.tags[key] = append(f.tags[key], tag)
+	sort.Strings(f.tags[key])
+	return nil
+}
+
+func (f *fakeStore) SetConnectionTags(ctx context.Context, userID, otherID uuid.UUID, tags []string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.connectedForTags(userID, otherID); err != nil {
+		return nil, err
+	}
+	set := append([]string{}, tags...)
+	sort.Strings(set)
+	f.tags[[2]uuid.UUID{userID, otherID}] = set
+	return append([]string{}, set...), nil
+}
+
+func (f *fakeStore) RemoveConnectionTag(ctx context.Context, userID, otherID uuid.UUID, tag string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := [2]uuid.UUID{userID, otherID}
+	for i, existing := range f.tags[key] {
+		if existing == tag {
+			f.tags[key] = append(f.tags[key][:i], f.tags[key][i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrTagNotFound
+}
+
+func (f *fakeStore) ListConnectionTags(ctx context.Context, userID uuid.UUID) ([]models.ConnectionTagCount, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counts := make(map[string]int)
+	for key, tags := range f.tags {
+		if key[0] != userID {
+			continue
+		}
+		for _, tag := range tags {
+			counts[tag]++
+		}
+	}
+
+	result := make([]models.ConnectionTagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, models.ConnectionTagCount{Tag: tag, Connections: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tag < result[j].Tag })
+	return result, nil
+}
+
+func (f *fakeStore) GetPendingConnectionRequests(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.ConnectionWithUser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requests := make([]models.ConnectionWithUser, 0)
+	for i := len(f.connections) - 1; i >= 0; i-- {
+		connection := f.connections[i]
+		if connection.AddresseeID == userID && connection.Status == models.StatusPending && connection.CreatedAt.After(since) {
+			requests = append(requests, f.withUser(connection, userID))
+		}
+	}
+	return requests, nil
+}
+
+func (f *fakeStore) ExpirePendingConnections(ctx context.Context, cutoff time.Time) ([]models.UserConnection, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	expired := make([]models.UserConnection, 0)
+	kept := f.connections[:0]
+	for _, connection := range f.connections {
+		if connection.Status == models.StatusPending && !connection.CreatedAt.After(cutoff) {
+			expired = append(expired, *connection)
+			continue
+		}
+		kept = append(kept, connection)
+	}
+	f.connections = kept
+	return expired, nil
+}
+
+// Blocks
+
+func (f *fakeStore) RelationshipState(ctx context.Context, userID, otherID uuid.UUID) (*models.Relationship, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rel := &models.Relationship{
+		BlockedByUser:  f.blocks[[2]uuid.UUID{userID, otherID}],
+		BlockedByOther: f.blocks[[2]uuid.UUID{otherID, userID}],
+	}
+	if connection := f.connectionBetween(userID, otherID); connection != nil {
+		found := *connection
+		rel.Connection = &found
+	}
+	return rel, nil
+}
+
+func (f *fakeStore) RelationshipStatuses(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) (map[uuid.UUID]models.RelationshipStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	statuses := make(map[uuid.UUID]models.RelationshipStatus, len(otherIDs))
+	for _, id := range otherIDs {
+		connection := f.connectionBetween(userID, id)
+		switch {
+		case f.blocked(userID, id):
+			statuses[id] = models.RelationshipBlocked
+		case connection != nil && connection.Status == models.StatusAccepted:
+			statuses[id] = models.RelationshipConnected
+		case connection != nil && connection.Status == models.StatusPending && connection.RequesterID == userID:
+			statuses[id] = models.RelationshipPendingOutgoing
+		case connection != nil && connection.Status == models.StatusPending:
+			statuses[id] = models.RelationshipPendingIncoming
+		default:
+			statuses[id] = models.RelationshipNone
+		}
+	}
+	return statuses, nil
+}
+
+func (f *fakeStore) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.blocks[[2]uuid.UUID{blockerID, blockedID}] = true
+	if connection := f.connectionBetween(blockerID, blockedID); connection != nil {
+		f.removeConnectionRow(connection)
+		f.recordEvent(connection.RequesterID, connection.AddresseeID, blockerID, models.ConnectionEventRemoved)
+	}
+	return nil
+}
+
+func (f *fakeStore) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := [2]uuid.UUID{blockerID, blockedID}
+	if !f.blocks[key] {
+		return fmt.Errorf("block not found")
+	}
+	delete(f.blocks, key)
+	return nil
+}
+
+// Admin
+
+func (f *fakeStore) GetAdminStats(ctx context.Context) (*models.AdminStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats := &models.AdminStats{
+		TotalUsers:     len(f.users),
+		SignupsPerDay:  make([]models.SignupCount, 0),
+		SignupsPerWeek: make([]models.SignupCount, 0),
+	}
+	for _, connection := range f.connections {
+		if connection.Status == models.StatusAccepted {
+			stats.TotalConnections++
+		}
+	}
+	return stats, nil
+}
+
+func (f *fakeStore) ListDisplayNameHistory(ctx context.Context, userID uuid.UUID) ([]models.DisplayNameChange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append(make([]models.DisplayNameChange, 0), f.nameHistory[userID]...), nil
+}
+
+func (f *fakeStore) ListUsers(ctx context.Context, filter database.ListUsersFilter) ([]models.UserAuth, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var users []models.UserAuth
+	for _, user := range f.users {
+		if (!filter.CreatedAfter.IsZero() && !user.CreatedAt.After(filter.CreatedAfter)) ||
+			(!filter.CreatedBefore.IsZero() && !user.CreatedAt.Before(filter.CreatedBefore)) {
+			continue
+		}
+		users = append(users, user.ToAuth())
+	}
+	sort.Slice(users, func(i, j int) bool {
+		less := users[i].CreatedAt.Before(users[j].CreatedAt)
+		if filter.SortBy == "username" {
+			less = users[i].Username < users[j].Username
+		}
+		if filter.Descending {
+			return !less
+		}
+		return less
+	})
+	return page(users, filter.Limit, filter.Offset), len(users), nil
+}
+
+// Invite codes
+
+func (f *fakeStore) CreateInviteCode(ctx context.Context, code string, createdBy uuid.UUID, expiresAt *time.Time) (*models.InviteCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	invite := &models.InviteCode{Code: code, CreatedBy: &createdBy, CreatedAt: time.Now(), ExpiresAt: expiresAt}
+	f.invites[code] = invite
+	created := *invite
+	return &created, nil
+}
+
+func (f *fakeStore) ListInviteCodes(ctx context.Context, limit, offset int) ([]models.InviteCode, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	invites := make([]models.InviteCode, 0, len(f.invites))
+	for _, invite := range f.invites {
+		invites = append(invites, *invite)
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].CreatedAt.After(invites[j].CreatedAt) })
+	return page(invites, limit, offset), len(invites), nil
+}
+
+func (f *fakeStore) CreateUserWithInvite(ctx context.Context, user *models.User, code string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	invite, ok := f.invites[code]
+	if !ok || invite.UsedAt != nil || (invite.ExpiresAt != nil && !invite.ExpiresAt.After(time.Now())) {
+		return database.ErrInvalidInviteCode
+	}
+	if err := f.insertUser(user); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	invite.UsedBy, invite.UsedAt = &user.ID, &now
+	*user = *f.users[user.ID]
+	return nil
+}
+
+// Sessions
+
+func (f *fakeStore) CreateSession(ctx context.Context, session *models.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session.CreatedAt = time.Now()
+	f.sessions[session.ID] = &fakeSession{Session: *session}
+	return nil
+}
+
+func (f *fakeStore) IsSessionActive(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[id]
+	return ok && session.UserID == userID && !session.revoked && session.ExpiresAt.After(time.Now()), nil
+}
+
+func (f *fakeStore) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sessions := make([]models.Session, 0)
+	for _, session := range f.sessions {
+		if session.UserID == userID && !session.revoked && session.ExpiresAt.After(time.Now()) {
+			sessions = append(sessions, session.Session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+func (f *fakeStore) RevokeSession(ctx context.Context, id, userID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[id]
+	if !ok || session.UserID != userID || session.revoked {
+		return fmt.Errorf("session not found")
+	}
+	session.revoked = true
+	return nil
+}
+
+func (f *fakeStore) RevokeAllSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var revoked int64
+	for _, session := range f.sessions {
+		if session.UserID == userID && !session.revoked && session.ExpiresAt.After(time.Now()) {
+			session.revoked = true
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// Notifications
+
+func (f *fakeStore) CreateNotification(ctx context.Context, userID uuid.UUID, notificationType string, payload json.RawMessage) (*models.Notification, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	notification := &models.Notification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      notificationType,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+	f.notifications = append(f.notifications, notification)
+	created := *notification
+	return &created, nil
+}
+
+func (f *fakeStore) ListNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	notifications := make([]models.Notification, 0)
+	for i := len(f.notifications) - 1; i >= 0; i-- {
+		n := f.notifications[i]
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			notifications = append(notifications, *n)
+		}
+	}
+	return page(notifications, limit, offset), len(notifications), nil
+}
+
+func (f *fakeStore) MarkNotificationRead(ctx context.Context, userID, notificationID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, n := range f.notifications {
+		if n.ID == notificationID && n.UserID == userID {
+			if n.ReadAt == nil {
+				now := time.Now()
+				n.ReadAt = &now
+			}
+			return nil
+		}
+	}
+	return errors.New("notification not found")
+}
+
+func (f *fakeStore) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var updated int64
+	now := time.Now()
+	for _, n := range f.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &now
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// page returns the slice of items selected by limit and offset, never nil
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return make([]T, 0)
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return append(make([]T, 0, len(items)), items...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,23 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"connectsphere-backend/internal/auth"
+	"connectsphere-backend/internal/config"
 	"connectsphere-backend/internal/database"
+	"connectsphere-backend/internal/events"
 	"connectsphere-backend/internal/models"
+	"connectsphere-backend/internal/webhooks"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,30 +25,74 @@ import (
 
 // Server represents the API server
 type Server struct {
-	db         *database.DB
+	db         Store
+	cfg        *config.Config
 	jwtManager *auth.JWTManager
+	hub        *events.Hub
+	webhooks   *webhooks.Dispatcher
+	build      BuildInfo
+
+	// readOnly rejects mutating API requests; toggled at runtime by admins
+	readOnly atomic.Bool
+
+	// cachedAdminStats holds the last GET /admin/stats result for ADMIN_STATS_CACHE_TTL
+	adminStatsMu     sync.Mutex
+	cachedAdminStats *models.AdminStats
 }
 
 // NewServer creates a new API server
-func NewServer(db *database.DB, jwtSecret string) *Server {
-	jwtManager := auth.NewJWTManager(jwtSecret, 24*time.Hour) // 24 hour token expiry
-	return &Server{
+func NewServer(db Store, cfg *config.Config) *Server {
+	jwtManager := auth.NewJWTManager(cfg.JWTSecret, cfg.JWTAlgorithm)
+	if cfg.SSOEnabled {
+		jwtManager.TrustIssuer(cfg.SSOIssuer, cfg.SSOAudience, auth.NewJWKS(cfg.SSOJWKSURL, cfg.SSOJWKSCacheTTL))
+	}
+
+	server := &Server{
 		db:         db,
+		cfg:        cfg,
 		jwtManager: jwtManager,
+		hub: events.NewHub(events.HubOptions{
+			HistorySize:      cfg.EventHistorySize,
+			MaxPerUser:       cfg.MaxEventStreamsPerUser,
+			SubscriberBuffer: cfg.EventSubscriberBuffer,
+		}),
 	}
+	server.readOnly.Store(cfg.ReadOnly)
+	if cfg.WebhookURL != "" {
+		server.webhooks = webhooks.NewDispatcher(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookEvents, max(cfg.WebhookMaxAttempts, 1))
+	}
+
+	return server
 }
 
 // SetupRoutes sets up all the API routes
 func (s *Server) SetupRoutes() *gin.Engine {
-	r := gin.Default()
+	// gin.Default() minus its logger: requestLogger redacts credentials
+	r := gin.New()
+	r.Use(gin.Recovery(), s.requestID(), s.negotiateLanguage(), s.requestLogger(), s.requestTimeout())
+	if s.cfg.CountDBQueries() {
+		r.Use(s.countQueries())
+	}
+
+	// Only trust X-Forwarded-For from known proxies so c.ClientIP() can't be spoofed
+	if err := r.SetTrustedProxies(s.cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// CORS middleware
+	allowMethods := strings.Join(s.cfg.CORSAllowedMethods, ", ")
+	allowHeaders := strings.Join(s.cfg.CORSAllowedHeaders, ", ")
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Methods", allowMethods)
+		c.Header("Access-Control-Allow-Headers", allowHeaders)
+		c.Header("Access-Control-Expose-Headers", "Location, Link, X-Total-Count, X-Request-ID, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, X-RateLimit-Warning, Retry-After, X-DB-Queries")
 
 		if c.Request.Method == "OPTIONS" {
+			// Let browsers skip the preflight for repeat requests
+			if s.cfg.CORSMaxAge > 0 {
+				c.Header("Access-Control-Max-Age", strconv.Itoa(s.cfg.CORSMaxAge))
+			}
 			c.AbortWithStatus(204)
 			return
 		}
@@ -47,14 +100,32 @@ func (s *Server) SetupRoutes() *gin.Engine {
 		c.Next()
 	})
 
+	// Readiness probe for load balancers: 503 while the database is unreachable
+	r.GET("/readyz", s.readyz)
+
 	// API v1 routes
 	v1 := r.Group("/api/v1")
+	v1.Use(s.readOnlyMode())
+
+	// Build details of the running server (public)
+	v1.GET("/version", s.version)
+	v1.GET("/openapi.json", s.getOpenAPISpec)
+
+	// Per-dependency health for admins and internal services
+	v1.GET("/health", s.serviceOrUserAuth(), s.serviceOrAdmin(), s.health)
 
 	// Auth routes (public)
 	auth := v1.Group("/auth")
 	{
 		auth.POST("/register", s.register)
 		auth.POST("/login", s.login)
+		auth.GET("/verify-email-change", s.verifyEmailChange)
+		if s.cfg.SSOEnabled {
+			auth.POST("/sso", s.ssoLogin)
+		}
+		if s.cfg.IntrospectionSecret != "" {
+			auth.POST("/token/introspect", s.serviceAuth(), s.introspectToken)
+		}
 	}
 
 	// Protected routes
@@ -63,33 +134,83 @@ func (s *Server) SetupRoutes() *gin.Engine {
 	{
 		users.GET("/me", s.getCurrentUser)
 		users.PUT("/me", s.updateProfile)
-		users.GET("/:id", s.getUserByID)
+		users.PATCH("/me", s.updateProfile)
+		users.PUT("/me/email", s.requestEmailChange)
+		users.GET("/me/sessions", s.listSessions)
+		users.DELETE("/me/sessions/:session_id", s.requireUUIDParam("session_id"), s.revokeSession)
+		users.POST("/me/logout-all", s.logoutAll)
+		users.GET("/:id", s.requireUUIDParam("id"), s.getUserByID)
+		users.POST("/batch", s.getUsersByIDs)
+		users.GET("/:id/connections", s.requireUUIDParam("id"), s.getConnectionsOfUser)
+		users.POST("/:id/block", s.requireUUIDParam("id"), s.blockUser)
+		users.DELETE("/:id/block", s.requireUUIDParam("id"), s.unblockUser)
 		users.GET("/search", s.searchUsers)
 	}
 
 	connections := v1.Group("/connections")
 	connections.Use(s.authMiddleware())
 	{
-		connections.POST("/send-request/:addressee_id", s.sendConnectionRequest)
-		connections.POST("/accept-request/:requester_id", s.acceptConnectionRequest)
-		connections.POST("/decline-request/:requester_id", s.declineConnectionRequest)
-		connections.DELETE("/remove-friend/:friend_id", s.removeConnection)
+		connections.POST("/send-request/:addressee_id", s.requireUUIDParam("addressee_id"), s.sendConnectionRequest)
+		connections.POST("/send-request-by-username", s.sendConnectionRequestByUsername)
+		connections.POST("/status", s.getConnectionStatuses)
+		connections.POST("/accept-request/:requester_id", s.requireUUIDParam("requester_id"), s.acceptConnectionRequest)
+		connections.POST("/decline-request/:requester_id", s.requireUUIDParam("requester_id"), s.declineConnectionRequest)
+		connections.DELETE("/remove-friend/:friend_id", s.requireUUIDParam("friend_id"), s.removeConnection)
 		connections.GET("", s.getConnections)
 		connections.GET("/pending", s.getPendingRequests)
+		connections.GET("/count", s.getConnectionCounts)
+		connections.GET("/all", s.getConnectionOverview)
+		connections.GET("/tags", s.listConnectionTags)
+		connections.PUT("/tags/:user_id/:tag", s.requireUUIDParam("user_id"), s.addConnectionTag)
+		connections.DELETE("/tags/:user_id/:tag", s.requireUUIDParam("user_id"), s.removeConnectionTag)
+		connections.PUT("/:friend_id/tags", s.requireUUIDParam("friend_id"), s.setConnectionTags)
+		connections.GET("/:connection_id", s.requireUUIDParam("connection_id"), s.getConnection)
+		connections.DELETE("/:connection_id", s.requireUUIDParam("connection_id"), s.removeConnectionByID)
+	}
+
+	notifications := v1.Group("/notifications")
+	notifications.Use(s.authMiddleware())
+	{
+		notifications.GET("", s.listNotifications)
+		notifications.POST("/read-all", s.markAllNotificationsRead)
+		notifications.POST("/:notification_id/read", s.requireUUIDParam("notification_id"), s.markNotificationRead)
+	}
+
+	// Live events over server-sent events
+	v1.GET("/events", s.tokenFromQuery(), s.authMiddleware(), s.streamEvents)
+
+	admin := v1.Group("/admin")
+	admin.Use(s.authMiddleware(), s.adminMiddleware())
+	{
+		admin.GET("/users", s.adminListUsers)
+		admin.GET("/users/:user_id/display-name-history", s.requireUUIDParam("user_id"), s.adminDisplayNameHistory)
+		admin.POST("/invite-codes", s.adminCreateInviteCode)
+		admin.GET("/invite-codes", s.adminListInviteCodes)
+		admin.GET("/connection-events/:user_id/:other_id", s.requireUUIDParam("user_id"), s.requireUUIDParam("other_id"), s.adminListConnectionEvents)
+		admin.GET("/stats", s.adminStats)
+		admin.GET("/events/stats", s.adminEventStats)
+		admin.GET("/maintenance", s.adminGetMaintenance)
+		admin.PUT("/maintenance", s.adminSetMaintenance)
 	}
 
 	return r
 }
 
+func (s *Server) readyz(c *gin.Context) {
+	if err := s.db.HealthCheck(c.Request.Context()); err != nil {
+		c.JSON(http.StatusServiceUnavailable, errorResponse(c, "not_ready", "Database is unavailable"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
 // Auth middleware to validate JWT tokens
 func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-				Error: "unauthorized",
-				Message: "Authorization header required",
-			})
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "unauthorized", "Authorization header required"))
 			c.Abort()
 			return
 		}
@@ -97,20 +218,48 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// Extract token from "Bearer <token>"
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-				Error: "unauthorized",
-				Message: "Invalid authorization header format",
-			})
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "unauthorized", "Invalid authorization header format"))
+			c.Abort()
+			return
+		}
+
+		// Turn away oversized or structurally broken tokens before parsing them
+		if !auth.WellFormed(tokenParts[1], s.cfg.JWTMaxLength) {
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "token_invalid", "Invalid token"))
 			c.Abort()
 			return
 		}
 
 		claims, err := s.jwtManager.ValidateToken(tokenParts[1])
+		if errors.Is(err, auth.ErrTokenExpired) {
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "token_expired", "Token has expired"))
+			c.Abort()
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-				Error: "unauthorized",
-				Message: "Invalid or expired token",
-			})
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "token_invalid", "Invalid token"))
+			c.Abort()
+			return
+		}
+		if !claims.IsAccessToken() {
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "token_invalid", "Only access tokens can be used to call the API"))
+			c.Abort()
+			return
+		}
+
+		// Reject tokens whose session was revoked. This is a primary key lookup on
+		// every authenticated request; it isn't cached, since a cache would let
+		// revoked tokens (see logoutAll) keep working until their entry expired.
+		sessionID, err := uuid.Parse(claims.ID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "token_invalid", "Invalid token"))
+			c.Abort()
+			return
+		}
+
+		active, err := s.db.IsSessionActive(c.Request.Context(), sessionID, claims.UserID)
+		if err != nil || !active {
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "unauthorized", "Session has been revoked or expired"))
 			c.Abort()
 			return
 		}
@@ -118,47 +267,100 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// Set user information in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("session_id", sessionID)
 		c.Next()
 	}
 }
 
+// validationError responds with 400 and the specific code of a models.ValidationError
+func (s *Server) validationError(c *gin.Context, err error) {
+	code := "invalid_request"
+	var verr *models.ValidationError
+	if errors.As(err, &verr) {
+		code = verr.Code
+	}
+
+	c.JSON(http.StatusBadRequest, errorResponse(c, code, err.Error()))
+}
+
+// displayNameAvailable writes a 409 and returns false if UNIQUE_DISPLAY_NAMES is on
+// and another user (other than excludeUserID) already has displayName
+func (s *Server) displayNameAvailable(c *gin.Context, displayName string, excludeUserID uuid.UUID) bool {
+	if !s.cfg.UniqueDisplayNames {
+		return true
+	}
+
+	taken, err := s.db.IsDisplayNameTaken(c.Request.Context(), displayName, excludeUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to check display name"))
+		return false
+	}
+	if taken {
+		displayNameTaken(c)
+		return false
+	}
+	return true
+}
+
+// displayNameTaken responds with the 409 for a display name used by another account
+func displayNameTaken(c *gin.Context) {
+	c.JSON(http.StatusConflict, errorResponse(c, "display_name_taken", "Display name is already taken"))
+}
+
+// created responds with 201 and a Location header pointing at the new resource
+func created(c *gin.Context, location string, body interface{}) {
+	c.Header("Location", location)
+	c.JSON(http.StatusCreated, body)
+}
+
 // Auth handlers
 
 func (s *Server) register(c *gin.Context) {
+	if s.cfg.RegistrationMode == "closed" {
+		c.JSON(http.StatusForbidden, errorResponse(c, "registration_closed", "Registration is closed"))
+		return
+	}
+
 	var req models.RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request",
-			Message: err.Error(),
-		})
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	req.Email = auth.NormalizeEmail(req.Email, s.cfg.CanonicalizeGmail)
+	if err := models.ValidateEmail(req.Email); err != nil {
+		s.validationError(c, err)
+		return
+	}
+	if err := models.ValidateUsername(req.Username, s.cfg.ReservedUsernames); err != nil {
+		s.validationError(c, err)
+		return
+	}
+	req.DisplayName = models.NormalizeDisplayName(req.DisplayName)
+	if err := models.ValidateDisplayName(req.DisplayName); err != nil {
+		s.validationError(c, err)
 		return
 	}
 
 	// Check if user already exists
 	if _, err := s.db.GetUserByEmail(c.Request.Context(), req.Email); err == nil {
-		c.JSON(http.StatusConflict, models.ErrorResponse{
-			Error: "user_exists",
-			Message: "User with this email already exists",
-		})
+		c.JSON(http.StatusConflict, errorResponse(c, "user_exists", "User with this email already exists"))
 		return
 	}
 
 	// Check if username is taken
 	if _, err := s.db.GetUserByUsername(c.Request.Context(), req.Username); err == nil {
-		c.JSON(http.StatusConflict, models.ErrorResponse{
-			Error: "username_taken",
-			Message: "Username is already taken",
-		})
+		c.JSON(http.StatusConflict, errorResponse(c, "username_taken", "Username is already taken"))
+		return
+	}
+
+	if !s.displayNameAvailable(c, req.DisplayName, uuid.Nil) {
 		return
 	}
 
 	// Hash password
-	hashedPassword, err := auth.HashPassword(req.Password)
+	hashedPassword, err := auth.HashPassword(req.Password, s.cfg.PasswordHashAlgo)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to hash password",
-		})
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to hash password"))
 		return
 	}
 
@@ -171,66 +373,94 @@ func (s *Server) register(c *gin.Context) {
 		HashedPassword: hashedPassword,
 	}
 
-	if err := s.db.CreateUser(c.Request.Context(), user); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to create user",
-		})
+	if s.cfg.RegistrationMode == "invite" {
+		err = s.db.CreateUserWithInvite(c.Request.Context(), user, req.InviteCode)
+	} else {
+		err = s.db.CreateUser(c.Request.Context(), user)
+	}
+	if err != nil {
+		if errors.Is(err, database.ErrDisplayNameTaken) {
+			displayNameTaken(c)
+			return
+		}
+		if errors.Is(err, database.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, errorResponse(c, "username_taken", "Username is already taken"))
+			return
+		}
+		if errors.Is(err, database.ErrInvalidInviteCode) {
+			c.JSON(http.StatusForbidden, errorResponse(c, "invalid_invite_code", "A valid, unused invite code is required to register"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to create user"))
 		return
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Email)
+	// Start a session and generate its JWT token
+	token, err := s.issueToken(c, user, s.cfg.TokenExpiry)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to generate token",
-		})
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to generate token"))
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.LoginResponse{
+	created(c, "/api/v1/users/"+user.ID.String(), models.LoginResponse{
 		Token: token,
 		User:  user.ToAuth(),
 	})
 }
 
+// loginPath stays open in read-only mode; see readOnlyExempt
+const loginPath = "/api/v1/auth/login"
+
 func (s *Server) login(c *gin.Context) {
 	var req models.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request",
-			Message: err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	// Get user by email
-	user, err := s.db.GetUserByEmail(c.Request.Context(), req.Email)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Error: "invalid_credentials",
-			Message: "Invalid email or password",
-		})
-		return
+	// Look up by email if the identifier looks like one, otherwise by username
+	identifier := req.Identifier
+	if identifier == "" {
+		identifier = req.Email
 	}
 
-	// Check password
-	if !auth.CheckPassword(user.HashedPassword, req.Password) {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Error: "invalid_credentials",
-			Message: "Invalid email or password",
-		})
+	var user *models.User
+	var err error
+	if strings.Contains(identifier, "@") {
+		email := auth.NormalizeEmail(identifier, s.cfg.CanonicalizeGmail)
+		user, err = s.db.GetUserByEmail(c.Request.Context(), email)
+		if err != nil && email != strings.TrimSpace(identifier) {
+			// Accounts created before EMAIL_CANONICALIZE_GMAIL was turned on keep their original address
+			user, err = s.db.GetUserByEmail(c.Request.Context(), strings.TrimSpace(identifier))
+		}
+	} else {
+		user, err = s.db.GetUserByUsername(c.Request.Context(), identifier)
+	}
+
+	// Same response for unknown users and wrong passwords
+	if err != nil || !auth.CheckPassword(user.HashedPassword, req.Password) {
+		c.JSON(http.StatusUnauthorized, errorResponse(c, "invalid_credentials", "Invalid username, email or password"))
 		return
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Email)
+	// Move the hash to the configured algorithm now that we have the plaintext.
+	// Skipped in read-only mode, which only lets login write the session.
+	if !s.readOnly.Load() && auth.NeedsRehash(user.HashedPassword, s.cfg.PasswordHashAlgo) {
+		if hashed, err := auth.HashPassword(req.Password, s.cfg.PasswordHashAlgo); err == nil {
+			if err := s.db.UpdatePasswordHash(c.Request.Context(), user.ID, hashed); err != nil {
+				log.Printf("Failed to rehash password for %s: %v", user.ID, err)
+			}
+		}
+	}
+
+	// Start a session, longer-lived when the user asked to be remembered
+	expiry := s.cfg.TokenExpiry
+	if req.Remember {
+		expiry = s.cfg.RememberTokenExpiry
+	}
+
+	token, err := s.issueToken(c, user, expiry)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to generate token",
-		})
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to generate token"))
 		return
 	}
 
@@ -247,10 +477,7 @@ func (s *Server) getCurrentUser(c *gin.Context) {
 
 	user, err := s.db.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: "user_not_found",
-			Message: "User not found",
-		})
+		c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
 		return
 	}
 
@@ -258,207 +485,621 @@ func (s *Server) getCurrentUser(c *gin.Context) {
 }
 
 func (s *Server) getUserByID(c *gin.Context) {
-	idParam := c.Param("id")
-	userID, err := uuid.Parse(idParam)
+	userID := uuidParam(c, "id")
+
+	user, err := s.db.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_id",
-			Message: "Invalid user ID format",
-		})
+		c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
 		return
 	}
 
-	user, err := s.db.GetUserByID(c.Request.Context(), userID)
+	viewerID := c.MustGet("user_id").(uuid.UUID)
+	if viewerID == user.ID {
+		c.JSON(http.StatusOK, user.ToConnectionView())
+		return
+	}
+
+	// Connections also see the status message, so check even for public profiles
+	connected, err := s.db.AreConnected(c.Request.Context(), viewerID, user.ID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: "user_not_found",
-			Message: "User not found",
-		})
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get user"))
+		return
+	}
+
+	view, ok := profileView(viewerID, user, connected)
+	if !ok {
+		// Private profiles are indistinguishable from missing users
+		c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// getConnectionsOfUser lists another user's connections. Only the user's own
+// connections may see them, and only if the user has show_connections on.
+func (s *Server) getConnectionsOfUser(c *gin.Context) {
+	viewerID := c.MustGet("user_id").(uuid.UUID)
+	ownerID := uuidParam(c, "id")
+
+	if viewerID != ownerID {
+		owner, err := s.db.GetUserByID(c.Request.Context(), ownerID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
+			return
+		}
+
+		rel, err := s.db.RelationshipState(c.Request.Context(), viewerID, ownerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get connections"))
+			return
+		}
+		connected := rel.Connection != nil && rel.Connection.Status == models.StatusAccepted
+
+		// Private profiles are indistinguishable from missing users
+		if _, ok := profileView(viewerID, owner, connected); !ok {
+			c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
+			return
+		}
+		if !connected || rel.Blocked() || !owner.ShowConnections {
+			c.JSON(http.StatusForbidden, errorResponse(c, "forbidden", "This user's connections are not visible to you"))
+			return
+		}
+	}
+
+	limit := 20
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	offset := 0
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	users, total, err := s.db.GetConnectionsOf(c.Request.Context(), ownerID, viewerID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get connections"))
 		return
 	}
 
-	c.JSON(http.StatusOK, user.ToPublic())
+	respondList(c, models.ListResponse[models.UserLimited]{
+		Data: users,
+		Pagination: models.Pagination{
+			Limit:  limit,
+			Offset: offset,
+			Total:  &total,
+		},
+	})
+}
+
+// getUsersByIDs is getUserByID for up to 100 users at once. Results keep the order
+// of the requested IDs so clients can match them up by position.
+func (s *Server) getUsersByIDs(c *gin.Context) {
+	viewerID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.BatchUsersRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(req.IDs))
+	for i, id := range req.IDs {
+		ids[i] = uuid.MustParse(id) // validated by the uuid binding rule
+	}
+
+	users, notFound, err := s.db.GetUsersByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get users"))
+		return
+	}
+
+	connected, err := s.db.ConnectedUserIDs(c.Request.Context(), viewerID, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get users"))
+		return
+	}
+
+	resp := models.BatchUsersResponse{
+		Data:     make([]any, 0, len(users)),
+		NotFound: notFound,
+	}
+	hidden := false
+	for _, user := range users {
+		view, ok := profileView(viewerID, user, connected[user.ID])
+		if !ok {
+			resp.NotFound = append(resp.NotFound, user.ID)
+			hidden = true
+			continue
+		}
+		resp.Data = append(resp.Data, view)
+	}
+	if hidden {
+		// Keep private profiles' IDs in request order among the missing ones
+		// rather than revealing them by where they sit in the list
+		position := make(map[uuid.UUID]int, len(ids))
+		for i := len(ids) - 1; i >= 0; i-- {
+			position[ids[i]] = i
+		}
+		sort.Slice(resp.NotFound, func(i, j int) bool {
+			return position[resp.NotFound[i]] < position[resp.NotFound[j]]
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// profileViews returns the view of each user that viewerID may see, skipping
+// profiles that are private to them
+func (s *Server) profileViews(c *gin.Context, viewerID uuid.UUID, users []*models.User) ([]any, error) {
+	ids := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	connected, err := s.db.ConnectedUserIDs(c.Request.Context(), viewerID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]any, 0, len(users))
+	for _, user := range users {
+		if view, ok := profileView(viewerID, user, connected[user.ID]); ok {
+			views = append(views, view)
+		}
+	}
+	return views, nil
+}
+
+// profileView returns the view of user that viewerID may see, or false if the
+// profile is private to them
+func profileView(viewerID uuid.UUID, user *models.User, connected bool) (any, bool) {
+	switch {
+	case viewerID == user.ID, connected:
+		return user.ToConnectionView(), true
+	case user.ProfileVisibility == models.VisibilityPublic:
+		return user.ToPublic(), true
+	case user.ProfileVisibility == models.VisibilityConnectionsOnly:
+		return user.ToLimited(), true
+	default:
+		return nil, false
+	}
 }
 
 func (s *Server) updateProfile(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
 
 	var req models.UpdateProfileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request",
-			Message: err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	if err := s.db.UpdateUser(c.Request.Context(), userID, req.DisplayName); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to update profile",
-		})
+	params := database.UpdateUserParams{
+		ProfileVisibility:       req.ProfileVisibility,
+		DiscoverableByEmail:     req.DiscoverableByEmail,
+		ConnectionRequestPolicy: req.ConnectionRequestPolicy,
+		ShowConnections:         req.ShowConnections,
+		ExpectedUpdatedAt:       req.ExpectedUpdatedAt,
+	}
+
+	if req.DisplayName != nil {
+		normalized := models.NormalizeDisplayName(*req.DisplayName)
+		req.DisplayName = &normalized
+		if err := models.ValidateDisplayName(*req.DisplayName); err != nil {
+			s.validationError(c, err)
+			return
+		}
+		if !s.displayNameAvailable(c, *req.DisplayName, userID) {
+			return
+		}
+		params.DisplayName = req.DisplayName
+		params.DisplayNameCooldown = s.cfg.DisplayNameChangeCooldown
+	}
+
+	if req.StatusMessage != nil {
+		normalized := models.NormalizeStatusMessage(*req.StatusMessage)
+		req.StatusMessage = &normalized
+		if err := models.ValidateStatusMessage(*req.StatusMessage); err != nil {
+			s.validationError(c, err)
+			return
+		}
+		params.StatusMessage = req.StatusMessage
+		if req.StatusExpiresIn != nil && normalized != "" {
+			expiresAt := time.Now().Add(time.Duration(*req.StatusExpiresIn) * time.Second)
+			params.StatusExpiresAt = &expiresAt
+		}
+	}
+
+	// Optimistic locking: refuse to overwrite edits made since the client's version
+	if header := c.GetHeader("If-Unmodified-Since"); header != "" {
+		since, err := http.ParseTime(header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "If-Unmodified-Since must be an HTTP date"))
+			return
+		}
+		params.UnmodifiedSince = &since
+	}
+
+	user, err := s.db.UpdateUser(c.Request.Context(), userID, params)
+	if err != nil {
+		var cooldown *database.DisplayNameCooldownError
+		if errors.As(err, &cooldown) {
+			setRetryAfter(c, cooldown.RetryAt)
+			c.JSON(http.StatusTooManyRequests, errorResponse(c, "display_name_cooldown", "Display name was changed recently. You can change it again after "+cooldown.RetryAt.UTC().Format(time.RFC3339)))
+			return
+		}
+		if errors.Is(err, database.ErrDisplayNameTaken) {
+			displayNameTaken(c)
+			return
+		}
+		if errors.Is(err, database.ErrProfileModified) {
+			c.JSON(http.StatusPreconditionFailed, errorResponse(c, "profile_modified", "The profile was changed by another request; reload it and try again"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to update profile"))
 		return
 	}
 
+	if req.StatusMessage != nil {
+		s.broadcastStatus(c, user)
+	}
+
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Profile updated successfully",
+		Data:    user.ToAuth(),
 	})
 }
 
 func (s *Server) searchUsers(c *gin.Context) {
-	query := c.Query("q")
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	// Trim and collapse runs of whitespace so "  john   doe " searches for "john doe"
+	query := strings.Join(strings.Fields(c.Query("q")), " ")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request",
-			Message: "Search query parameter 'q' is required",
-		})
+		c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Search query parameter 'q' is required"))
 		return
 	}
 
-	limit := 20 // Default limit
+	// Exact email lookup never exposes the email itself and returns at most one user
+	switch c.DefaultQuery("by", "name") {
+	case "name":
+	case "email":
+		// Stored emails are canonical, so the lookup has to be too
+		email := auth.NormalizeEmail(query, s.cfg.CanonicalizeGmail)
+		users, err := s.db.FindUserByEmail(c.Request.Context(), userID, email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to search users"))
+			return
+		}
+		views, err := s.profileViews(c, userID, users)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to search users"))
+			return
+		}
+		respondList(c, models.NewListResponse(views))
+		return
+	default:
+		c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Search parameter 'by' must be 'name' or 'email'"))
+		return
+	}
+
+	// Out of range limits are rejected rather than clamped so clients know they got fewer results than asked for
+	limit := s.cfg.SearchDefaultLimit
 	if limitParam := c.Query("limit"); limitParam != "" {
-		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
-			limit = parsedLimit
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit < 1 || parsedLimit > s.cfg.SearchMaxLimit {
+			c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", fmt.Sprintf("Search parameter 'limit' must be between 1 and %d", s.cfg.SearchMaxLimit)))
+			return
 		}
+		limit = parsedLimit
 	}
 
-	users, err := s.db.SearchUsers(c.Request.Context(), query, limit)
+	offset := 0
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	users, err := s.db.SearchUsers(c.Request.Context(), userID, query, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to search users",
-		})
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to search users"))
+		return
+	}
+	views, err := s.profileViews(c, userID, users)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to search users"))
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	respondList(c, models.ListResponse[any]{
+		Data: views,
+		Pagination: models.Pagination{
+			Limit:  limit,
+			Offset: offset,
+		},
+	})
 }
 
 // Connection handlers
 
 func (s *Server) sendConnectionRequest(c *gin.Context) {
 	requesterID := c.MustGet("user_id").(uuid.UUID)
-	
-	addresseeIDParam := c.Param("addressee_id")
-	addresseeID, err := uuid.Parse(addresseeIDParam)
+
+	addresseeID := uuidParam(c, "addressee_id")
+
+	// Can't send request to yourself
+	if requesterID == addresseeID {
+		c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Cannot send connection request to yourself"))
+		return
+	}
+
+	// Check if addressee exists
+	addressee, err := s.db.GetUserByID(c.Request.Context(), addresseeID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_id",
-			Message: "Invalid addressee ID format",
-		})
+		c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
+		return
+	}
+
+	s.sendConnectionRequestTo(c, requesterID, addressee)
+}
+
+// sendConnectionRequestByUsername is sendConnectionRequest for clients that only
+// know the other user's username, e.g. from a shared profile link
+func (s *Server) sendConnectionRequestByUsername(c *gin.Context) {
+	requesterID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.SendRequestByUsernameRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	addressee, err := s.db.GetUserByUsername(c.Request.Context(), strings.TrimSpace(req.Username))
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorResponse(c, "user_not_found", "User not found"))
 		return
 	}
 
 	// Can't send request to yourself
-	if requesterID == addresseeID {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_request",
-			Message: "Cannot send connection request to yourself",
-		})
+	if requesterID == addressee.ID {
+		c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_request", "Cannot send connection request to yourself"))
 		return
 	}
 
-	// Check if addressee exists
-	if _, err := s.db.GetUserByID(c.Request.Context(), addresseeID); err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: "user_not_found",
-			Message: "User not found",
-		})
+	s.sendConnectionRequestTo(c, requesterID, addressee)
+}
+
+// sendConnectionRequestTo runs the checks shared by both send endpoints and creates
+// the request, or accepts the addressee's pending request to us
+func (s *Server) sendConnectionRequestTo(c *gin.Context, requesterID uuid.UUID, addressee *models.User) {
+	addresseeID := addressee.ID
+
+	rel, err := s.db.RelationshipState(c.Request.Context(), requesterID, addresseeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to send connection request"))
 		return
 	}
 
-	// Check if connection already exists
-	if _, err := s.db.GetConnection(c.Request.Context(), requesterID, addresseeID); err == nil {
-		c.JSON(http.StatusConflict, models.ErrorResponse{
-			Error: "connection_exists",
-			Message: "Connection request already exists",
-		})
+	// Blocks apply in both directions and don't reveal who blocked whom
+	if rel.Blocked() {
+		c.JSON(http.StatusForbidden, errorResponse(c, "blocked", "You cannot send a connection request to this user"))
 		return
 	}
 
-	if err := s.db.CreateConnection(c.Request.Context(), requesterID, addresseeID); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to send connection request",
+	// Check if connection already exists, telling the client what state it is in
+	existing := rel.Connection
+	if existing != nil {
+		// The other user already asked us: accept their request instead of creating a duplicate
+		if existing.Status == models.StatusPending && existing.RequesterID == addresseeID {
+			connection, err := s.db.AcceptConnection(c.Request.Context(), addresseeID, requesterID, s.cfg.MaxConnections)
+			if s.connectionLimitReached(c, err, requesterID) {
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to accept existing connection request"))
+				return
+			}
+
+			s.notifyConnectionAccepted(c, addresseeID, requesterID)
+
+			c.JSON(http.StatusOK, models.SuccessResponse{
+				Message: "connection established",
+				Data:    connection,
+			})
+			return
+		}
+
+		switch {
+		case existing.Status == models.StatusDeclined && existing.RequesterID == addresseeID:
+			// We declined them earlier; asking them ourselves is fine
+			existing = nil
+		case existing.Status == models.StatusDeclined:
+			retryAt := existing.UpdatedAt.Add(s.cfg.DeclinedRequestCooldown)
+			if time.Now().Before(retryAt) {
+				c.JSON(http.StatusConflict, errorResponse(c, "request_declined", "This user declined your request recently. You can send a new one after "+retryAt.UTC().Format(time.RFC3339)))
+				return
+			}
+			existing = nil
+		}
+	}
+	if existing != nil {
+		direction := "incoming"
+		if existing.RequesterID == requesterID {
+			direction = "outgoing"
+		}
+
+		c.JSON(http.StatusConflict, models.ConnectionExistsResponse{
+			ErrorResponse: errorResponse(c, "connection_exists", "Connection request already exists"),
+			Status:        existing.Status,
+			Direction:     direction,
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.SuccessResponse{
+	// Respect the addressee's choice of who may ask them
+	if !s.acceptsRequestsFrom(c, addressee, requesterID) {
+		return
+	}
+
+	// Limit how many requests a user can send per window to curb spam
+	if s.cfg.ConnectionRequestLimit > 0 {
+		now := time.Now()
+		sent, oldest, err := s.db.CountConnectionRequestsSince(c.Request.Context(), requesterID, now.Add(-s.cfg.ConnectionRequestWindow))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to send connection request"))
+			return
+		}
+
+		// A slot frees up when the oldest request in the window ages out; with none
+		// sent yet, the one about to be sent starts the window
+		reset := now.Add(s.cfg.ConnectionRequestWindow)
+		if oldest != nil {
+			reset = oldest.Add(s.cfg.ConnectionRequestWindow)
+		}
+
+		if sent >= s.cfg.ConnectionRequestLimit {
+			setRateLimitHeaders(c, s.cfg.ConnectionRequestLimit, 0, reset)
+			setRetryAfter(c, reset)
+			c.JSON(http.StatusTooManyRequests, errorResponse(c, "rate_limited", "Too many connection requests sent, try again later"))
+			return
+		}
+		// The request about to be created counts against what's left
+		remaining := s.cfg.ConnectionRequestLimit - sent - 1
+		setRateLimitHeaders(c, s.cfg.ConnectionRequestLimit, remaining, reset)
+		setRateLimitWarning(c, s.cfg.ConnectionRequestLimit, remaining, s.cfg.RateLimitWarningPercent)
+	}
+
+	connection, err := s.db.CreateConnection(c.Request.Context(), requesterID, addresseeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to send connection request"))
+		return
+	}
+
+	if requester, err := s.db.GetUserByID(c.Request.Context(), requesterID); err == nil {
+		s.notify(c.Request.Context(), addresseeID, "connection_request", gin.H{
+			"connection": connection,
+			"user":       requester.ToPublic(),
+		})
+	}
+
+	created(c, "/api/v1/connections/"+connection.ID.String(), models.SuccessResponse{
 		Message: "Connection request sent successfully",
+		Data:    connection,
+	})
+}
+
+// acceptsRequestsFrom enforces the addressee's connection_request_policy, writing a
+// 403 and returning false if the requester may not send them a request
+func (s *Server) acceptsRequestsFrom(c *gin.Context, addressee *models.User, requesterID uuid.UUID) bool {
+	switch addressee.ConnectionRequestPolicy {
+	case models.RequestPolicyNobody:
+		c.JSON(http.StatusForbidden, errorResponse(c, "requests_disabled", "This user is not accepting connection requests"))
+		return false
+	case models.RequestPolicyConnectionsOfConnections:
+		mutual, err := s.db.CountMutualConnections(c.Request.Context(), requesterID, addressee.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to send connection request"))
+			return false
+		}
+		if mutual == 0 {
+			c.JSON(http.StatusForbidden, errorResponse(c, "requests_restricted", "This user only accepts connection requests from connections of their connections"))
+			return false
+		}
+	}
+	return true
+}
+
+// connectionLimitReached writes a 409 and returns true if err is a connection cap
+// error, telling the current user whether they or the other party are at the limit
+func (s *Server) connectionLimitReached(c *gin.Context, err error, currentUserID uuid.UUID) bool {
+	var limitErr *database.ConnectionLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+
+	message := "This user has reached the maximum number of connections"
+	if limitErr.UserID == currentUserID {
+		message = "You have reached the maximum number of connections"
+	}
+
+	c.JSON(http.StatusConflict, models.ConnectionLimitResponse{
+		ErrorResponse: errorResponse(c, "connection_limit_reached", message),
+		UserID:        limitErr.UserID,
 	})
+	return true
 }
 
 func (s *Server) acceptConnectionRequest(c *gin.Context) {
 	addresseeID := c.MustGet("user_id").(uuid.UUID)
-	
-	requesterIDParam := c.Param("requester_id")
-	requesterID, err := uuid.Parse(requesterIDParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_id",
-			Message: "Invalid requester ID format",
-		})
+
+	requesterID := uuidParam(c, "requester_id")
+
+	connection, err := s.db.AcceptConnection(c.Request.Context(), requesterID, addresseeID, s.cfg.MaxConnections)
+	if s.connectionLimitReached(c, err, addresseeID) {
 		return
 	}
-
-	if err := s.db.AcceptConnection(c.Request.Context(), requesterID, addresseeID); err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: "request_not_found",
-			Message: "Pending connection request not found",
-		})
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorResponse(c, "request_not_found", "Pending connection request not found"))
 		return
 	}
 
+	s.notifyConnectionAccepted(c, requesterID, addresseeID)
+
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Connection request accepted successfully",
+		Data:    connection,
 	})
 }
 
 func (s *Server) declineConnectionRequest(c *gin.Context) {
 	addresseeID := c.MustGet("user_id").(uuid.UUID)
-	
-	requesterIDParam := c.Param("requester_id")
-	requesterID, err := uuid.Parse(requesterIDParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_id",
-			Message: "Invalid requester ID format",
-		})
-		return
-	}
 
-	if err := s.db.DeclineConnection(c.Request.Context(), requesterID, addresseeID); err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: "request_not_found",
-			Message: "Pending connection request not found",
-		})
+	requesterID := uuidParam(c, "requester_id")
+
+	connection, err := s.db.DeclineConnection(c.Request.Context(), requesterID, addresseeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorResponse(c, "request_not_found", "Pending connection request not found"))
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Connection request declined successfully",
+		Data:    connection,
 	})
 }
 
 func (s *Server) removeConnection(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
-	
-	friendIDParam := c.Param("friend_id")
-	friendID, err := uuid.Parse(friendIDParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "invalid_id",
-			Message: "Invalid friend ID format",
-		})
+
+	friendID := uuidParam(c, "friend_id")
+
+	if err := s.db.RemoveConnection(c.Request.Context(), userID, friendID); err != nil {
+		c.JSON(http.StatusNotFound, errorResponse(c, "friendship_not_found", "Friendship not found"))
 		return
 	}
 
-	if err := s.db.RemoveConnection(c.Request.Context(), userID, friendID); err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Error: "friendship_not_found",
-			Message: "Friendship not found",
-		})
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Friendship removed successfully",
+	})
+}
+
+func (s *Server) removeConnectionByID(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	connectionID := uuidParam(c, "connection_id")
+
+	err := s.db.RemoveConnectionByID(c.Request.Context(), connectionID, userID)
+	switch {
+	case errors.Is(err, database.ErrConnectionNotFound):
+		c.JSON(http.StatusNotFound, errorResponse(c, "connection_not_found", "Connection not found"))
+		return
+	case errors.Is(err, database.ErrNotConnectionParticipant):
+		c.JSON(http.StatusForbidden, errorResponse(c, "forbidden", "You are not part of this connection"))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to remove connection"))
 		return
 	}
 
@@ -470,29 +1111,121 @@ func (s *Server) removeConnection(c *gin.Context) {
 func (s *Server) getConnections(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
 
-	connections, err := s.db.GetUserConnections(c.Request.Context(), userID)
+	// Optionally only the connections the user tagged, e.g. ?tag=close
+	tag := models.NormalizeTag(c.Query("tag"))
+	if tag != "" {
+		if err := models.ValidateTag(tag); err != nil {
+			s.validationError(c, err)
+			return
+		}
+	}
+
+	connections, err := s.db.GetUserConnections(c.Request.Context(), userID, tag)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to get connections",
-		})
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get connections"))
 		return
 	}
 
-	c.JSON(http.StatusOK, connections)
+	respondList(c, models.NewListResponse(connections))
 }
 
 func (s *Server) getPendingRequests(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
 
-	requests, err := s.db.GetPendingConnectionRequests(c.Request.Context(), userID)
+	// Hide requests past their expiry even if the expiry job hasn't removed them yet
+	var since time.Time
+	if s.cfg.ConnectionRequestExpiry > 0 {
+		since = time.Now().Add(-s.cfg.ConnectionRequestExpiry)
+	}
+
+	requests, err := s.db.GetPendingConnectionRequests(c.Request.Context(), userID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get pending requests"))
+		return
+	}
+
+	respondList(c, models.NewListResponse(requests))
+}
+
+// getConnectionStatuses returns the caller's relationship with up to 100 users at
+// once, so lists of users can show the right action button for each
+func (s *Server) getConnectionStatuses(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.ConnectionStatusRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(req.UserIDs))
+	for i, id := range req.UserIDs {
+		ids[i] = uuid.MustParse(id) // validated by the uuid binding rule
+	}
+
+	statuses, err := s.db.RelationshipStatuses(c.Request.Context(), userID, ids)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "internal_error",
-			Message: "Failed to get pending requests",
-		})
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get connection statuses"))
 		return
 	}
+	if _, ok := statuses[userID]; ok {
+		statuses[userID] = models.RelationshipSelf
+	}
+
+	c.JSON(http.StatusOK, models.ConnectionStatusResponse{Statuses: statuses})
+}
+
+func (s *Server) getConnectionOverview(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	// limit and offset apply to each section separately
+	limit := 20
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
 
-	c.JSON(http.StatusOK, requests)
+	offset := 0
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	overview, err := s.db.GetConnectionOverview(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get connections"))
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
+}
+
+func (s *Server) getConnectionCounts(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	counts, err := s.db.GetConnectionCounts(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to count connections"))
+		return
+	}
+
+	c.JSON(http.StatusOK, counts)
+}
+
+func (s *Server) getConnection(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	connectionID := uuidParam(c, "connection_id")
+
+	// Only the two parties can see a connection; anyone else gets the same 404
+	connection, err := s.db.GetConnectionByID(c.Request.Context(), connectionID)
+	if err != nil || (connection.RequesterID != userID && connection.AddresseeID != userID) {
+		c.JSON(http.StatusNotFound, errorResponse(c, "connection_not_found", "Connection not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Connection retrieved successfully",
+		Data:    connection,
+	})
 }
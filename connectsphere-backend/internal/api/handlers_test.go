@@ -0,0 +1,458 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"connectsphere-backend/internal/auth"
+	"connectsphere-backend/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestRegister(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.newUser("taken")
+
+	tests := []struct {
+		name   string
+		body   map[string]string
+		status int
+		code   string
+	}{
+		{
+			name:   "created",
+			body:   map[string]string{"username": "alice", "display_name": "Alice", "email": "Alice@Example.com", "password": "password123"},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "missing password",
+			body:   map[string]string{"username": "bob", "display_name": "Bob", "email": "bob@example.com"},
+			status: http.StatusBadRequest,
+			code:   "validation_failed",
+		},
+		{
+			name:   "email in use",
+			body:   map[string]string{"username": "carol", "display_name": "Carol", "email": "TAKEN@example.com", "password": "password123"},
+			status: http.StatusConflict,
+			code:   "user_exists",
+		},
+		{
+			name:   "username in use",
+			body:   map[string]string{"username": "Taken", "display_name": "Dave", "email": "dave@example.com", "password": "password123"},
+			status: http.StatusConflict,
+			code:   "username_taken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodPost, "/api/v1/auth/register", "", tt.body)
+			if tt.code != "" {
+				expectError(t, rec, tt.status, tt.code)
+				return
+			}
+			expectStatus(t, rec, tt.status)
+
+			resp := decode[models.LoginResponse](t, rec)
+			if resp.Token == "" || resp.User.Email != "alice@example.com" {
+				t.Fatalf("response = %+v, want a token and the normalized email", resp)
+			}
+			if got := rec.Header().Get("Location"); got != "/api/v1/users/"+resp.User.ID.String() {
+				t.Errorf("Location = %q", got)
+			}
+		})
+	}
+}
+
+func TestRegisterClosed(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"REGISTRATION_MODE": "closed"})
+
+	rec := ts.do(t, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"username": "alice", "display_name": "Alice", "email": "alice@example.com", "password": "password123",
+	})
+	expectError(t, rec, http.StatusForbidden, "registration_closed")
+}
+
+func TestLogin(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.newUserWithPassword(t, "alice", "password123")
+
+	tests := []struct {
+		name       string
+		identifier string
+		password   string
+		status     int
+	}{
+		{"by email", "ALICE@example.com", "password123", http.StatusOK},
+		{"by username", "alice", "password123", http.StatusOK},
+		{"wrong password", "alice", "password124", http.StatusUnauthorized},
+		{"unknown user", "bob", "password123", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+				"identifier": tt.identifier, "password": tt.password,
+			})
+			if tt.status != http.StatusOK {
+				expectError(t, rec, tt.status, "invalid_credentials")
+				return
+			}
+			expectStatus(t, rec, http.StatusOK)
+
+			token := decode[models.LoginResponse](t, rec).Token
+			expectStatus(t, ts.do(t, http.MethodGet, "/api/v1/users/me", token, nil), http.StatusOK)
+		})
+	}
+}
+
+func TestLoginRemember(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"TOKEN_EXPIRY": "2h", "REMEMBER_TOKEN_EXPIRY": "720h"})
+	ts.newUserWithPassword(t, "alice", "password123")
+
+	tests := []struct {
+		name     string
+		remember bool
+		expiry   time.Duration
+	}{
+		{"default expiry", false, 2 * time.Hour},
+		{"remembered", true, 720 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now().Truncate(time.Second)
+			rec := ts.do(t, http.MethodPost, "/api/v1/auth/login", "", map[string]any{
+				"identifier": "alice", "password": "password123", "remember": tt.remember,
+			})
+			expectStatus(t, rec, http.StatusOK)
+
+			claims, err := ts.jwtManager.ValidateToken(decode[models.LoginResponse](t, rec).Token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expiresAt := claims.ExpiresAt.Time
+			if earliest, latest := before.Add(tt.expiry), time.Now().Add(tt.expiry); expiresAt.Before(earliest) || expiresAt.After(latest) {
+				t.Fatalf("token expires at %v, want between %v and %v", expiresAt, earliest, latest)
+			}
+
+			// The session must not outlive or be outlived by its token
+			session := ts.store.sessions[uuid.MustParse(claims.ID)]
+			if diff := session.ExpiresAt.Sub(expiresAt); diff < -time.Second || diff > time.Second {
+				t.Fatalf("session expires at %v, token at %v", session.ExpiresAt, expiresAt)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice := ts.newUser("alice")
+	token := ts.tokenFor(t, alice)
+
+	expired, err := ts.jwtManager.GenerateToken(alice.ID, alice.Email, uuid.New(), -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := auth.NewJWTManager("not-the-server-secret", "HS256").GenerateToken(alice.ID, alice.Email, uuid.New(), -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		code   string
+	}{
+		{"missing header", "", "unauthorized"},
+		{"expired", "Bearer " + expired, "token_expired"},
+		{"expired and signed with another secret", "Bearer " + forged, "token_invalid"},
+		{"not bearer", "Basic " + token, "unauthorized"},
+		{"malformed token", "Bearer not-a-jwt", "token_invalid"},
+		{"tampered signature", "Bearer " + token + "x", "token_invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			expectError(t, ts.serve(req), http.StatusUnauthorized, tt.code)
+		})
+	}
+
+	t.Run("refresh token", func(t *testing.T) {
+		refresh := signedToken(t, ts, auth.Claims{
+			UserID:           alice.ID,
+			Email:            alice.Email,
+			Version:          auth.ClaimsVersion,
+			TokenType:        "refresh",
+			RegisteredClaims: sessionClaims(t, ts, alice),
+		})
+		for _, path := range []string{"/api/v1/users/me", "/api/v1/connections", "/api/v1/notifications"} {
+			expectError(t, ts.do(t, http.MethodGet, path, refresh, nil), http.StatusUnauthorized, "token_invalid")
+		}
+	})
+
+	t.Run("token without a type", func(t *testing.T) {
+		// Issued before token_type existed, and still an access token
+		legacy := signedToken(t, ts, auth.Claims{UserID: alice.ID, Email: alice.Email, RegisteredClaims: sessionClaims(t, ts, alice)})
+		expectStatus(t, ts.do(t, http.MethodGet, "/api/v1/users/me", legacy, nil), http.StatusOK)
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		expectStatus(t, ts.do(t, http.MethodPost, "/api/v1/users/me/logout-all", token, nil), http.StatusOK)
+		expectError(t, ts.do(t, http.MethodGet, "/api/v1/users/me", token, nil), http.StatusUnauthorized, "unauthorized")
+	})
+}
+
+func TestEmptyListsAreArrays(t *testing.T) {
+	ts := newTestServer(t, nil)
+	token := ts.tokenFor(t, ts.newUser("alice"))
+
+	for _, path := range []string{
+		"/api/v1/connections",
+		"/api/v1/connections/pending",
+		"/api/v1/users/search?q=nobody",
+		"/api/v1/users/search?q=nobody@example.com&by=email",
+		"/api/v1/notifications",
+	} {
+		t.Run(path, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, path, token, nil)
+			expectStatus(t, rec, http.StatusOK)
+
+			body := decode[map[string]json.RawMessage](t, rec)
+			if got := string(body["data"]); got != "[]" {
+				t.Fatalf("data = %s, want []", got)
+			}
+		})
+	}
+}
+
+func TestSendConnectionRequestAcceptsReverseRequest(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob := ts.newUser("alice"), ts.newUser("bob")
+	aliceToken, bobToken := ts.tokenFor(t, alice), ts.tokenFor(t, bob)
+
+	rec := ts.do(t, http.MethodPost, "/api/v1/connections/send-request/"+bob.ID.String(), aliceToken, nil)
+	expectStatus(t, rec, http.StatusCreated)
+
+	rec = ts.do(t, http.MethodPost, "/api/v1/connections/send-request/"+alice.ID.String(), bobToken, nil)
+	expectStatus(t, rec, http.StatusOK)
+	resp := decode[struct {
+		Message string                `json:"message"`
+		Data    models.UserConnection `json:"data"`
+	}](t, rec)
+	if resp.Message != "connection established" {
+		t.Fatalf("message = %q, want connection established", resp.Message)
+	}
+	// Alice's original request was accepted rather than a second row created
+	if resp.Data.RequesterID != alice.ID || resp.Data.Status != models.StatusAccepted {
+		t.Fatalf("connection = %+v, want alice's request accepted", resp.Data)
+	}
+	if len(ts.store.connections) != 1 {
+		t.Fatalf("%d connection rows, want 1", len(ts.store.connections))
+	}
+
+	rec = ts.do(t, http.MethodPost, "/api/v1/connections/send-request/"+bob.ID.String(), aliceToken, nil)
+	expectError(t, rec, http.StatusConflict, "connection_exists")
+	if exists := decode[models.ConnectionExistsResponse](t, rec); exists.Status != models.StatusAccepted {
+		t.Fatalf("status = %s, want accepted", exists.Status)
+	}
+}
+
+func TestSendConnectionRequestRelationships(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice := ts.newUser("alice")
+	token := ts.tokenFor(t, alice)
+
+	stranger, friend, blocker, blocked := ts.newUser("stranger"), ts.newUser("friend"), ts.newUser("blocker"), ts.newUser("blocked")
+	ts.store.addConnection(friend.ID, alice.ID, models.StatusAccepted)
+	ts.store.addBlock(blocker.ID, alice.ID)
+	ts.store.addBlock(alice.ID, blocked.ID)
+
+	tests := []struct {
+		name   string
+		other  *models.User
+		status int
+		code   string
+	}{
+		{"stranger", stranger, http.StatusCreated, ""},
+		{"connected", friend, http.StatusConflict, "connection_exists"},
+		{"blocked by the other user", blocker, http.StatusForbidden, "blocked"},
+		{"blocked by the user", blocked, http.StatusForbidden, "blocked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodPost, "/api/v1/connections/send-request/"+tt.other.ID.String(), token, nil)
+			if tt.code == "" {
+				expectStatus(t, rec, tt.status)
+				return
+			}
+			expectError(t, rec, tt.status, tt.code)
+		})
+	}
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"PASSWORD_HASH_ALGO": "argon2id"})
+	bcryptHash, err := auth.HashPassword("password123", auth.AlgoBcrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := ts.store.addUser(models.User{Username: "alice", DisplayName: "Alice", Email: "alice@example.com", HashedPassword: bcryptHash})
+
+	login := func() *httptest.ResponseRecorder {
+		return ts.do(t, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"identifier": "alice", "password": "password123"})
+	}
+
+	expectStatus(t, login(), http.StatusOK)
+	upgraded := ts.store.users[alice.ID].HashedPassword
+	if !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("hash after login = %q, want an argon2id hash", upgraded)
+	}
+
+	// The upgraded hash still verifies, and is not rehashed again
+	expectStatus(t, login(), http.StatusOK)
+	if got := ts.store.users[alice.ID].HashedPassword; got != upgraded {
+		t.Fatal("an up to date hash was replaced on login")
+	}
+}
+
+func TestDisplayNameValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		displayName string
+		code        string // empty when the name is accepted
+	}{
+		{"100 emoji", strings.Repeat("😀", 100), ""},
+		{"101 emoji", strings.Repeat("😀", 101), "invalid_display_name"},
+		{"decomposed accents count as one character each", strings.Repeat("e\u0301", 100), ""},
+		{"right-to-left override", "evil\u202egnp.exe", "invalid_display_name"},
+		{"only spaces", "   ", "invalid_display_name"},
+		{"only tabs and newlines", "\t\n", "invalid_display_name"},
+		{"surrounding whitespace is trimmed", " \tAlice Smith\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run("register/"+tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			rec := ts.do(t, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+				"username": "alice", "display_name": tt.displayName, "email": "alice@example.com", "password": "password123",
+			})
+			if tt.code != "" {
+				expectError(t, rec, http.StatusBadRequest, tt.code)
+				return
+			}
+			expectStatus(t, rec, http.StatusCreated)
+			if got := decode[models.LoginResponse](t, rec).User.DisplayName; got != models.NormalizeDisplayName(tt.displayName) {
+				t.Fatalf("stored display name %q, want the trimmed NFC form", got)
+			}
+		})
+
+		t.Run("update/"+tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			token := ts.tokenFor(t, ts.newUser("alice"))
+			rec := ts.do(t, http.MethodPatch, "/api/v1/users/me", token, map[string]string{"display_name": tt.displayName})
+			if tt.code != "" {
+				expectError(t, rec, http.StatusBadRequest, tt.code)
+				return
+			}
+			expectStatus(t, rec, http.StatusOK)
+			resp := decode[struct {
+				Data models.UserAuth `json:"data"`
+			}](t, rec)
+			if got := resp.Data.DisplayName; got != models.NormalizeDisplayName(tt.displayName) {
+				t.Fatalf("stored display name %q, want the trimmed NFC form", got)
+			}
+		})
+	}
+}
+
+func TestGetUsersByIDs(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob, carol := ts.newUser("alice"), ts.newUser("bob"), ts.newUser("carol")
+	hidden := ts.store.addUser(models.User{Username: "hidden", DisplayName: "hidden", Email: "hidden@example.com", ProfileVisibility: models.VisibilityPrivate})
+	unknown := uuid.New()
+	token := ts.tokenFor(t, alice)
+
+	rec := ts.do(t, http.MethodPost, "/api/v1/users/batch", token, map[string][]string{
+		"ids": {carol.ID.String(), hidden.ID.String(), unknown.String(), bob.ID.String(), carol.ID.String(), unknown.String()},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	resp := decode[struct {
+		Data     []models.UserPublic `json:"data"`
+		NotFound []uuid.UUID         `json:"not_found"`
+	}](t, rec)
+
+	// Request order, each user once; the private profile reads as missing, in its requested position
+	var got []string
+	for _, user := range resp.Data {
+		got = append(got, user.Username)
+	}
+	if fmt.Sprint(got) != "[carol bob]" {
+		t.Fatalf("data = %v, want [carol bob]", got)
+	}
+	if want := fmt.Sprint([]uuid.UUID{hidden.ID, unknown}); fmt.Sprint(resp.NotFound) != want {
+		t.Fatalf("not_found = %v, want %v", resp.NotFound, want)
+	}
+
+	rec = ts.do(t, http.MethodPost, "/api/v1/users/batch", token, map[string][]string{"ids": {"not-a-uuid"}})
+	expectError(t, rec, http.StatusBadRequest, "validation_failed")
+	rec = ts.do(t, http.MethodPost, "/api/v1/users/batch", token, map[string][]string{"ids": {}})
+	expectError(t, rec, http.StatusBadRequest, "validation_failed")
+}
+
+func TestGetConnectionCounts(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob, carol, dave := ts.newUser("alice"), ts.newUser("bob"), ts.newUser("carol"), ts.newUser("dave")
+	ts.store.addConnection(alice.ID, bob.ID, models.StatusAccepted)
+	ts.store.addConnection(carol.ID, alice.ID, models.StatusPending)
+	ts.store.addConnection(alice.ID, dave.ID, models.StatusPending)
+
+	rec := ts.do(t, http.MethodGet, "/api/v1/connections/count", ts.tokenFor(t, alice), nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	// The counts are the whole body, not wrapped in a success envelope
+	var body map[string]int
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", rec.Body, err)
+	}
+	want := map[string]int{"connections": 1, "pending_incoming": 1, "pending_outgoing": 1}
+	if !reflect.DeepEqual(body, want) {
+		t.Fatalf("body = %v, want %v", body, want)
+	}
+}
+
+// sessionClaims starts a session for user and returns registered claims naming it
+func sessionClaims(t *testing.T, ts *testServer, user *models.User) jwt.RegisteredClaims {
+	t.Helper()
+
+	session := &models.Session{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := ts.store.CreateSession(context.Background(), session); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	return jwt.RegisteredClaims{ID: session.ID.String(), ExpiresAt: jwt.NewNumericDate(session.ExpiresAt)}
+}
+
+// signedToken signs claims the way the server signs its own tokens
+func signedToken(t *testing.T, ts *testServer, claims auth.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.GetSigningMethod(ts.cfg.JWTAlgorithm), claims).SignedString([]byte(ts.cfg.JWTSecret))
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return token
+}
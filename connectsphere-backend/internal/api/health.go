@@ -0,0 +1,115 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slowDatabaseThreshold is the ping latency above which the database is degraded
+const slowDatabaseThreshold = 500 * time.Millisecond
+
+// healthStatusRank orders statuses so the overall status is the worst component's
+var healthStatusRank = map[string]int{
+	models.HealthOK:       0,
+	models.HealthDegraded: 1,
+	models.HealthDown:     2,
+}
+
+// isServiceRequest reports whether the request presents INTROSPECTION_SECRET as
+// its bearer token. It is always false while the secret is unset.
+func (s *Server) isServiceRequest(c *gin.Context) bool {
+	if s.cfg.IntrospectionSecret == "" {
+		return false
+	}
+	secret, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(secret), []byte(s.cfg.IntrospectionSecret)) == 1
+}
+
+// serviceOrUserAuth admits internal services without a session; everyone else
+// goes through authMiddleware
+func (s *Server) serviceOrUserAuth() gin.HandlerFunc {
+	authenticate := s.authMiddleware()
+	return func(c *gin.Context) {
+		if s.isServiceRequest(c) {
+			c.Set("service", true)
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// serviceOrAdmin follows serviceOrUserAuth, requiring users to be admins
+func (s *Server) serviceOrAdmin() gin.HandlerFunc {
+	requireAdmin := s.adminMiddleware()
+	return func(c *gin.Context) {
+		if c.GetBool("service") {
+			c.Next()
+			return
+		}
+		requireAdmin(c)
+	}
+}
+
+// health checks each dependency and reports its status and latency, for
+// diagnosing partial outages. Like readyz it answers 503 when anything is down.
+func (s *Server) health(c *gin.Context) {
+	report := models.HealthReport{
+		Status: models.HealthOK,
+		Components: map[string]models.HealthComponent{
+			"database": s.checkDatabase(c),
+			"hub":      s.checkHub(),
+		},
+		CheckedAt: time.Now(),
+	}
+	for _, component := range report.Components {
+		if healthStatusRank[component.Status] > healthStatusRank[report.Status] {
+			report.Status = component.Status
+		}
+	}
+
+	status := http.StatusOK
+	if report.Status == models.HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+func (s *Server) checkDatabase(c *gin.Context) models.HealthComponent {
+	start := time.Now()
+	err := s.db.HealthCheck(c.Request.Context())
+	latency := time.Since(start)
+
+	component := models.HealthComponent{Status: models.HealthOK, LatencyMS: milliseconds(latency)}
+	switch {
+	case err != nil:
+		component.Status = models.HealthDown
+		component.Error = err.Error()
+	case latency > slowDatabaseThreshold:
+		component.Status = models.HealthDegraded
+	}
+	return component
+}
+
+// checkHub reports the in-process event hub, which is up whenever the server is
+func (s *Server) checkHub() models.HealthComponent {
+	start := time.Now()
+	subscribers := s.hub.Stats().Subscribers
+
+	return models.HealthComponent{
+		Status:      models.HealthOK,
+		LatencyMS:   milliseconds(time.Since(start)),
+		Subscribers: &subscribers,
+	}
+}
+
+// milliseconds converts d to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectsphere-backend/internal/auth"
+	"connectsphere-backend/internal/config"
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testServer is a Server wired to a fakeStore, with its routes set up
+type testServer struct {
+	*Server
+	store  *fakeStore
+	router *gin.Engine
+}
+
+// testConfig loads the default configuration, with env overriding defaults as in production
+func testConfig(t *testing.T, env map[string]string) *config.Config {
+	t.Helper()
+
+	t.Setenv("DATABASE_URL", "postgres://localhost/connectsphere_test")
+	t.Setenv("JWT_SECRET", "test-secret-that-is-long-enough-for-hs256")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	return config.Load("")
+}
+
+// newTestServer returns a server backed by an empty fakeStore, configured from
+// the defaults plus env
+func newTestServer(t *testing.T, env map[string]string) *testServer {
+	t.Helper()
+
+	store := newFakeStore()
+	server := NewServer(store, testConfig(t, env))
+	store.uniqueDisplayNames = server.cfg.UniqueDisplayNames
+	return &testServer{Server: server, store: store, router: server.SetupRoutes()}
+}
+
+// newUser stores a public user with the given username and returns it
+func (ts *testServer) newUser(username string) *models.User {
+	return ts.store.addUser(models.User{
+		Username:    username,
+		DisplayName: username,
+		Email:       username + "@example.com",
+	})
+}
+
+// newUserWithPassword stores a user who can log in with password
+func (ts *testServer) newUserWithPassword(t *testing.T, username, password string) *models.User {
+	t.Helper()
+
+	hashed, err := auth.HashPassword(password, ts.cfg.PasswordHashAlgo)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	return ts.store.addUser(models.User{
+		Username:       username,
+		DisplayName:    username,
+		Email:          username + "@example.com",
+		HashedPassword: hashed,
+	})
+}
+
+// tokenFor starts a session for user and returns its access token
+func (ts *testServer) tokenFor(t *testing.T, user *models.User) string {
+	t.Helper()
+
+	session := &models.Session{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := ts.store.CreateSession(context.Background(), session); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	token, err := ts.jwtManager.GenerateToken(user.ID, user.Email, session.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+// do sends a request through the router. body is JSON-encoded unless it is nil
+// or already a string; token is sent as a bearer token unless empty.
+func (ts *testServer) do(t *testing.T, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return ts.serve(req)
+}
+
+// serve sends a prepared request through the router
+func (ts *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	ts.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals the response body into a T, failing the test on error
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+// expectError checks the response status and error code
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, status, rec.Body.String())
+	}
+	if got := decode[models.ErrorResponse](t, rec).Error; got != code {
+		t.Fatalf("error = %q, want %q", got, code)
+	}
+}
+
+// expectStatus checks the response status
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, status, rec.Body.String())
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"connectsphere-backend/internal/messages"
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// negotiateLanguage picks the response language from Accept-Language and stores it
+// in the context for errorResponse
+func (s *Server) negotiateLanguage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := messages.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set("lang", lang)
+		c.Header("Content-Language", lang)
+		c.Header("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// errorResponse builds an ErrorResponse in the request's language. English keeps
+// message, which is often more specific than the catalog entry; other languages
+// use the catalog entry for code. The code itself is never translated.
+func errorResponse(c *gin.Context, code, message string) models.ErrorResponse {
+	if lang := c.GetString("lang"); lang != "" && lang != messages.DefaultLanguage {
+		if localized := messages.Localize(code, lang); localized != "" {
+			message = localized
+		}
+	}
+	return models.ErrorResponse{
+		Error:   code,
+		Message: message,
+	}
+}
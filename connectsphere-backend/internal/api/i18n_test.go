@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"connectsphere-backend/internal/messages"
+)
+
+// errorCode matches the literal error codes passed to errorResponse and set on ValidationErrors
+var errorCode = regexp.MustCompile(`(?:errorResponse\(c, |Code:\s+)"([a-z_]+)"`)
+
+func TestErrorCodesAreLocalized(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, "../models/validation.go")
+
+	codes := make(map[string]bool)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range errorCode.FindAllStringSubmatch(string(source), -1) {
+			codes[match[1]] = true
+		}
+	}
+	if len(codes) == 0 {
+		t.Fatal("found no error codes")
+	}
+
+	for code := range codes {
+		if messages.Localize(code, "es") == "" {
+			t.Errorf("error code %s has no catalog message", code)
+		}
+	}
+}
+
+func TestErrorResponseLanguage(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		lang           string
+		message        string
+	}{
+		// English keeps the handler's own, more specific message
+		{"no header", "", "en", "Authorization header required"},
+		{"Spanish", "es-ES,es;q=0.9", "es", "Se requiere autenticación"},
+		{"unsupported language", "fr", "en", "Authorization header required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := ts.serve(req)
+			expectError(t, rec, http.StatusUnauthorized, "unauthorized")
+			if got := rec.Header().Get("Content-Language"); got != tt.lang {
+				t.Errorf("Content-Language = %q, want %q", got, tt.lang)
+			}
+			if got := decode[map[string]string](t, rec)["message"]; got != tt.message {
+				t.Errorf("message = %q, want %q", got, tt.message)
+			}
+		})
+	}
+}
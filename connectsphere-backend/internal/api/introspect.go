@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+
+	"connectsphere-backend/internal/auth"
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// introspectPath is registered only when INTROSPECTION_SECRET is set
+const introspectPath = "/api/v1/auth/token/introspect"
+
+// serviceAuth admits internal services presenting INTROSPECTION_SECRET as a bearer token
+func (s *Server) serviceAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.isServiceRequest(c) {
+			c.JSON(http.StatusUnauthorized, errorResponse(c, "unauthorized", "A valid service credential is required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// introspectToken lets other services check a session token the same way
+// authMiddleware does, in the style of RFC 7662. Any token that would be refused
+// (malformed, expired, bad signature, not an access token, revoked session) is
+// simply inactive, without saying why.
+func (s *Server) introspectToken(c *gin.Context) {
+	var req models.IntrospectionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	inactive := models.IntrospectionResponse{Active: false}
+
+	if !auth.WellFormed(req.Token, s.cfg.JWTMaxLength) {
+		c.JSON(http.StatusOK, inactive)
+		return
+	}
+
+	claims, err := s.jwtManager.ValidateToken(req.Token)
+	if err != nil || !claims.IsAccessToken() {
+		c.JSON(http.StatusOK, inactive)
+		return
+	}
+
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, inactive)
+		return
+	}
+
+	active, err := s.db.IsSessionActive(c.Request.Context(), sessionID, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to introspect token"))
+		return
+	}
+	if !active {
+		c.JSON(http.StatusOK, inactive)
+		return
+	}
+
+	resp := models.IntrospectionResponse{
+		Active:    true,
+		Subject:   &claims.UserID,
+		Email:     claims.Email,
+		SessionID: &sessionID,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.IssuedAt = claims.IssuedAt.Unix()
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLoggedBodyBytes caps how much of a request body is written to the log
+const maxLoggedBodyBytes = 4096
+
+// requestLogger logs one line per request. Headers listed in LogRedactHeaders are
+// masked, and bodies are only logged when enabled and the path doesn't match
+// LogNoBodyRoutes, so credentials sent to auth endpoints never reach the log
+func (s *Server) requestLogger() gin.HandlerFunc {
+	redact := make(map[string]bool, len(s.cfg.LogRedactHeaders))
+	for _, header := range s.cfg.LogRedactHeaders {
+		redact[http.CanonicalHeaderKey(header)] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		var body string
+		if s.cfg.LogRequestBodies && c.Request.Body != nil && !matchesRoute(path, s.cfg.LogNoBodyRoutes) {
+			raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBodyBytes))
+			if err == nil {
+				body = string(raw)
+				// Hand the handler the full body: what we read, followed by the unread remainder
+				c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(raw), c.Request.Body), c.Request.Body}
+			}
+		}
+
+		c.Next()
+
+		line := []string{
+			c.Request.Method,
+			path,
+			time.Since(start).String(),
+			c.ClientIP(),
+			"request_id=" + c.GetString("request_id"),
+		}
+		if headers := formatHeaders(c.Request.Header, redact); headers != "" {
+			line = append(line, "headers="+headers)
+		}
+		if body != "" {
+			line = append(line, "body="+body)
+		}
+		log.Printf("[HTTP] %d %s", c.Writer.Status(), strings.Join(line, " | "))
+	}
+}
+
+// matchesRoute reports whether path matches any pattern; a trailing "*" matches by prefix
+func matchesRoute(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// formatHeaders renders headers as name=value pairs with redacted values masked
+func formatHeaders(header http.Header, redact map[string]bool) string {
+	var pairs []string
+	for name, values := range header {
+		value := strings.Join(values, ",")
+		if redact[name] {
+			value = "[REDACTED]"
+		}
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// readCloser pairs a replacement reader with the original body's Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLog redirects the standard logger to a buffer for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(output) })
+	return &buf
+}
+
+func TestRequestLoggerRedactsCredentials(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"LOG_REQUEST_BODIES": "true"})
+	alice := ts.newUserWithPassword(t, "alice", "correct-horse-battery")
+	token := ts.tokenFor(t, alice)
+	logged := captureLog(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		secrets []string // must never appear in the log
+		logged  []string // must appear in the log
+	}{
+		{
+			name:    "login with the right password",
+			path:    "/api/v1/auth/login",
+			body:    `{"identifier":"alice","password":"correct-horse-battery"}`,
+			secrets: []string{"correct-horse-battery"},
+		},
+		{
+			name:    "login with a wrong password",
+			path:    "/api/v1/auth/login",
+			body:    `{"identifier":"alice","password":"wrong-horse-battery"}`,
+			secrets: []string{"wrong-horse-battery"},
+		},
+		{
+			name:    "register",
+			path:    "/api/v1/auth/register",
+			body:    `{"username":"bob","display_name":"Bob","email":"bob@example.com","password":"bobs-secret-password"}`,
+			secrets: []string{"bobs-secret-password"},
+		},
+		{
+			name:    "bodies of other routes are logged",
+			path:    "/api/v1/users/batch",
+			body:    `{"ids":["` + alice.ID.String() + `"]}`,
+			secrets: []string{token},
+			logged:  []string{alice.ID.String(), "Authorization=[REDACTED]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged.Reset()
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			ts.serve(req)
+
+			output := logged.String()
+			if !strings.Contains(output, tt.path) {
+				t.Fatalf("request was not logged: %q", output)
+			}
+			for _, secret := range tt.secrets {
+				if strings.Contains(output, secret) {
+					t.Errorf("log contains %q: %s", secret, output)
+				}
+			}
+			for _, want := range tt.logged {
+				if !strings.Contains(output, want) {
+					t.Errorf("log does not contain %q: %s", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchesRoute(t *testing.T) {
+	patterns := []string{"/api/v1/auth/*", "/api/v1/users/me/email"}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/api/v1/auth/login", true},
+		{"/api/v1/auth/", true},
+		{"/api/v1/users/me/email", true},
+		{"/api/v1/users/me/email/extra", false},
+		{"/api/v1/users/me", false},
+		{"/api/v1/authx", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesRoute(tt.path, patterns); got != tt.want {
+			t.Errorf("matchesRoute(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFormatHeaders(t *testing.T) {
+	redact := map[string]bool{"Authorization": true, "Cookie": true}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"redacted", http.Header{"Authorization": {"Bearer secret"}}, "Authorization=[REDACTED]"},
+		{"multiple values redacted", http.Header{"Cookie": {"a=1", "b=2"}}, "Cookie=[REDACTED]"},
+		{"kept", http.Header{"Accept-Language": {"es", "en"}}, "Accept-Language=es,en"},
+		{"empty", http.Header{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatHeaders(tt.header, redact); got != tt.want {
+				t.Fatalf("formatHeaders = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
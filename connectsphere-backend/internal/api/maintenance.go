@@ -0,0 +1,75 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maintenancePath stays writable in read-only mode so admins can turn it off
+const maintenancePath = "/api/v1/admin/maintenance"
+
+// readOnlyExempt are the non-GET routes allowed in read-only mode: turning it off,
+// token introspection, which only reads despite being a POST, and password login,
+// which only adds a session row so users (and admins) can still sign in
+var readOnlyExempt = map[string]bool{
+	maintenancePath: true,
+	introspectPath:  true,
+	loginPath:       true,
+}
+
+// readOnlyWrites are the GET routes that write, which read-only mode blocks too
+var readOnlyWrites = map[string]bool{
+	verifyEmailChangePath: true,
+}
+
+// readOnlyMode rejects mutating requests with 503 maintenance while read-only
+// mode is on, e.g. during schema migrations. Reads keep working.
+func (s *Server) readOnlyMode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if !readOnlyWrites[c.FullPath()] {
+				c.Next()
+				return
+			}
+		}
+
+		if s.readOnly.Load() && !readOnlyExempt[c.FullPath()] {
+			c.Header("Retry-After", "60")
+			c.JSON(http.StatusServiceUnavailable, errorResponse(c, "maintenance", "The service is in read-only maintenance mode"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (s *Server) adminGetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Maintenance mode retrieved successfully",
+		Data:    models.MaintenanceStatus{ReadOnly: s.readOnly.Load()},
+	})
+}
+
+func (s *Server) adminSetMaintenance(c *gin.Context) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+
+	var req models.MaintenanceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	s.readOnly.Store(*req.ReadOnly)
+	log.Printf("Read-only mode set to %t by %s", *req.ReadOnly, adminID)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Maintenance mode updated successfully",
+		Data:    models.MaintenanceStatus{ReadOnly: *req.ReadOnly},
+	})
+}
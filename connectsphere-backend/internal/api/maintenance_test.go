@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"connectsphere-backend/internal/models"
+)
+
+func TestReadOnlyMode(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"READ_ONLY": "true"})
+	alice, bob := ts.newUser("alice"), ts.newUser("bob")
+	token := ts.tokenFor(t, alice)
+	admin := ts.store.addUser(models.User{Username: "admin", DisplayName: "admin", Email: "admin@example.com", IsAdmin: true})
+	ts.newUserWithPassword(t, "carol", "password123")
+	adminToken := ts.tokenFor(t, admin)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   any
+		status int
+	}{
+		{"reads keep working", http.MethodGet, "/api/v1/users/me", token, nil, http.StatusOK},
+		{"writes are refused", http.MethodPost, "/api/v1/connections/send-request/" + bob.ID.String(), token, nil, http.StatusServiceUnavailable},
+		{"login only adds a session", http.MethodPost, "/api/v1/auth/login", "", map[string]string{"identifier": "carol", "password": "password123"}, http.StatusOK},
+		{"registration is refused", http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+			"username": "dave", "display_name": "Dave", "email": "dave@example.com", "password": "password123",
+		}, http.StatusServiceUnavailable},
+		{"email change confirmation writes despite being a GET", http.MethodGet, "/api/v1/auth/verify-email-change?token=abc", "", nil, http.StatusServiceUnavailable},
+		{"admins can still turn it off", http.MethodPut, "/api/v1/admin/maintenance", adminToken, map[string]bool{"read_only": false}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, tt.method, tt.path, tt.token, tt.body)
+			if tt.status == http.StatusServiceUnavailable {
+				expectError(t, rec, tt.status, "maintenance")
+				return
+			}
+			expectStatus(t, rec, tt.status)
+		})
+	}
+
+	// Once it is off the confirmation link is handled again, here as an unknown token
+	rec := ts.do(t, http.MethodGet, "/api/v1/auth/verify-email-change?token=abc", "", nil)
+	if rec.Code == http.StatusServiceUnavailable {
+		t.Fatalf("status = %d after leaving read-only mode", rec.Code)
+	}
+}
+
+func TestConnectionRequestExpiryPausedWhileReadOnly(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"READ_ONLY": "true", "CONNECTION_REQUEST_EXPIRY": "24h"})
+	alice, bob := ts.newUser("alice"), ts.newUser("bob")
+	connection := ts.store.addConnection(alice.ID, bob.ID, models.StatusPending)
+	connection.CreatedAt = time.Now().Add(-48 * time.Hour)
+
+	ts.expireConnectionRequests(context.Background())
+	if len(ts.store.connections) != 1 {
+		t.Fatal("a request was expired in read-only mode")
+	}
+
+	ts.readOnly.Store(false)
+	ts.expireConnectionRequests(context.Background())
+	if len(ts.store.connections) != 0 {
+		t.Fatal("the stale request was not expired after leaving read-only mode")
+	}
+	if got := ts.store.notificationsFor(alice.ID); len(got) != 1 || got[0] != "connection_request_expired" {
+		t.Fatalf("alice's notifications = %v, want connection_request_expired", got)
+	}
+}
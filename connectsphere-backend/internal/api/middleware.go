@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requireUUIDParam parses the named path parameter as a UUID and stores it in the
+// context for uuidParam, aborting with a uniform 400 if it is malformed
+func (s *Server) requireUUIDParam(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param(name))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(c, "invalid_id", "Path parameter '"+name+"' must be a valid UUID"))
+			c.Abort()
+			return
+		}
+
+		c.Set("param:"+name, id)
+		c.Next()
+	}
+}
+
+// uuidParam returns a path parameter validated by requireUUIDParam
+func uuidParam(c *gin.Context, name string) uuid.UUID {
+	return c.MustGet("param:" + name).(uuid.UUID)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Notification handlers
+
+func (s *Server) listNotifications(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	limit := 20
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+	offset := 0
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, err := s.db.ListNotifications(c.Request.Context(), userID, unreadOnly, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to get notifications"))
+		return
+	}
+
+	respondList(c, models.ListResponse[models.Notification]{
+		Data: notifications,
+		Pagination: models.Pagination{
+			Limit:  limit,
+			Offset: offset,
+			Total:  &total,
+		},
+	})
+}
+
+func (s *Server) markNotificationRead(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	notificationID := uuidParam(c, "notification_id")
+
+	if err := s.db.MarkNotificationRead(c.Request.Context(), userID, notificationID); err != nil {
+		c.JSON(http.StatusNotFound, errorResponse(c, "notification_not_found", "Notification not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Notification marked as read",
+	})
+}
+
+func (s *Server) markAllNotificationsRead(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	updated, err := s.db.MarkAllNotificationsRead(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to mark notifications as read"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "All notifications marked as read",
+		Data:    gin.H{"updated": updated},
+	})
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec is the hand-maintained OpenAPI 3 description of the API. Update it
+// together with any route, request or response change.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (s *Server) getOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// ginParam matches a gin path parameter such as :user_id
+var ginParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json does not parse: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q, want 3.x", spec.OpenAPI)
+	}
+
+	// Optional routes are registered so the spec is checked against all of them
+	ts := newTestServer(t, map[string]string{
+		"SSO_ENABLED":          "true",
+		"SSO_JWKS_URL":         "http://127.0.0.1/jwks.json",
+		"SSO_ISSUER":           "https://sso.example.com",
+		"INTROSPECTION_SECRET": "introspection-secret",
+	})
+
+	routes := make(map[string]bool)
+	for _, route := range ts.router.Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		routes[method+" "+path] = true
+
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("%s %s is not in openapi.json", route.Method, path)
+		}
+	}
+
+	// And the other way round: no documented operation without a route
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			switch method {
+			case "parameters", "summary", "description":
+				continue
+			}
+			if !routes[method+" "+path] {
+				t.Errorf("openapi.json documents %s %s, which has no route", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	rec := ts.do(t, http.MethodGet, "/api/v1/openapi.json", "", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Fatal("GET /api/v1/openapi.json did not return JSON")
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondList writes a list response with the pagination also exposed as headers
+// for tooling that doesn't read the body: X-Total-Count when the total is known,
+// and Link with rel="next"/"prev" URLs built from the current request.
+func respondList[T any](c *gin.Context, list models.ListResponse[T]) {
+	page := list.Pagination
+	if page.Total != nil {
+		c.Header("X-Total-Count", strconv.Itoa(*page.Total))
+	}
+
+	if page.Limit > 0 {
+		var links []string
+
+		hasNext := len(list.Data) == page.Limit
+		if page.Total != nil {
+			hasNext = page.Offset+page.Limit < *page.Total
+		}
+		if hasNext {
+			links = append(links, pageLink(c, page.Limit, page.Offset+page.Limit, "next"))
+		}
+		if page.Offset > 0 {
+			links = append(links, pageLink(c, page.Limit, max(page.Offset-page.Limit, 0), "prev"))
+		}
+
+		if len(links) > 0 {
+			c.Header("Link", strings.Join(links, ", "))
+		}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// pageLink formats a Link header entry for the current URL with limit and offset replaced
+func pageLink(c *gin.Context, limit, offset int, rel string) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	u.RawQuery = query.Encode()
+
+	return "<" + u.RequestURI() + `>; rel="` + rel + `"`
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"log"
+	"strconv"
+
+	"connectsphere-backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dbQueriesHeader reports how many queries a request had run when its response started
+const dbQueriesHeader = "X-DB-Queries"
+
+// countQueries counts the database queries each request runs and logs a warning
+// when a request runs more than DB_QUERY_WARN_THRESHOLD, which usually means an
+// N+1 loop. In debug mode the count is also sent as X-DB-Queries. Queries run
+// after the response has started are logged but can't be in the header.
+func (s *Server) countQueries() gin.HandlerFunc {
+	exposeHeader := s.cfg.GinMode == gin.DebugMode
+
+	return func(c *gin.Context) {
+		ctx, counter := database.WithQueryCounter(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+
+		if exposeHeader {
+			original := c.Writer
+			c.Writer = &queryCountWriter{ResponseWriter: original, counter: counter}
+			defer func() { c.Writer = original }()
+		}
+
+		c.Next()
+
+		if threshold := s.cfg.DBQueryWarnThreshold; threshold > 0 && counter.Count() > int64(threshold) {
+			log.Printf("[DB] request_id=%s %s %s ran %d queries (DB_QUERY_WARN_THRESHOLD=%d), possible N+1",
+				c.GetString("request_id"), c.Request.Method, c.FullPath(), counter.Count(), threshold)
+		}
+	}
+}
+
+// queryCountWriter sets X-DB-Queries just before the response headers are sent
+type queryCountWriter struct {
+	gin.ResponseWriter
+	counter *database.QueryCounter
+}
+
+func (w *queryCountWriter) setHeader() {
+	if !w.ResponseWriter.Written() {
+		w.ResponseWriter.Header().Set(dbQueriesHeader, strconv.FormatInt(w.counter.Count(), 10))
+	}
+}
+
+func (w *queryCountWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *queryCountWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *queryCountWriter) WriteString(data string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(data)
+}
+
+func (w *queryCountWriter) Flush() {
+	w.setHeader()
+	w.ResponseWriter.Flush()
+}
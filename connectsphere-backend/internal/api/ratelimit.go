@@ -0,0 +1,39 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setRateLimitHeaders tells clients where they stand against a limit so they can
+// back off before hitting it: the limit, how many calls remain in the current
+// window, and when (Unix seconds) another call becomes available
+func setRateLimitHeaders(c *gin.Context, limit, remaining int, reset time.Time) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+// setRateLimitWarning adds an advisory X-RateLimit-Warning once remaining calls
+// drop to warnPercent of the limit or below, so clients can slow down before
+// they are refused. warnPercent 0 disables the warning.
+func setRateLimitWarning(c *gin.Context, limit, remaining, warnPercent int) {
+	if warnPercent > 0 && remaining*100 <= limit*warnPercent {
+		c.Header("X-RateLimit-Warning", "approaching limit")
+	}
+}
+
+// setRetryAfter sets Retry-After to the whole seconds until reset (at least 1)
+func setRetryAfter(c *gin.Context, reset time.Time) {
+	seconds := int(math.Ceil(time.Until(reset).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"connectsphere-backend/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries the request ID in both directions
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs so they can't bloat the logs
+const maxRequestIDLength = 128
+
+// requestID tags every request with an ID, reusing the client's X-Request-ID when it
+// sends a reasonable one. The ID is echoed in the response and stored in the request
+// context for the request logger and the database query tracer.
+func (s *Server) requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+
+		c.Header(requestIDHeader, id)
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
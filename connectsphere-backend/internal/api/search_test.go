@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// recordingStore records the query and limit of each SearchUsers call
+type recordingStore struct {
+	*fakeStore
+	queries []string
+	limits  []int
+}
+
+func (s *recordingStore) SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit, offset int) ([]*models.User, error) {
+	s.queries = append(s.queries, query)
+	s.limits = append(s.limits, limit)
+	return s.fakeStore.SearchUsers(ctx, viewerID, query, limit, offset)
+}
+
+func TestSearchQueryWhitespace(t *testing.T) {
+	store := &recordingStore{fakeStore: newFakeStore()}
+	server := NewServer(store, testConfig(t, nil))
+	ts := &testServer{Server: server, store: store.fakeStore, router: server.SetupRoutes()}
+	token := ts.tokenFor(t, ts.newUser("viewer"))
+	ts.store.addUser(models.User{Username: "doe_john", DisplayName: "John Doe", Email: "john@example.com"})
+
+	tests := []struct {
+		name  string
+		q     string
+		query string // passed to the store; empty when the request is rejected
+	}{
+		{"surrounding spaces", "  john  ", "john"},
+		{"internal runs of spaces", "  john   doe ", "john doe"},
+		{"tabs and newlines", "john\t\ndoe", "john doe"},
+		{"only spaces", "   ", ""},
+		{"only tabs and newlines", "\t\n", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.queries = nil
+			rec := ts.do(t, http.MethodGet, "/api/v1/users/search?q="+url.QueryEscape(tt.q), token, nil)
+			if tt.query == "" {
+				expectError(t, rec, http.StatusBadRequest, "invalid_request")
+				if len(store.queries) != 0 {
+					t.Fatalf("rejected query reached the store: %q", store.queries)
+				}
+				return
+			}
+
+			expectStatus(t, rec, http.StatusOK)
+			if len(store.queries) != 1 || store.queries[0] != tt.query {
+				t.Fatalf("store searched for %q, want %q", store.queries, tt.query)
+			}
+			// Every word has to match, in any order, against username or display name
+			users := decode[models.ListResponse[models.UserPublic]](t, rec).Data
+			if len(users) != 1 || users[0].Username != "doe_john" {
+				t.Fatalf("results = %+v, want doe_john", users)
+			}
+		})
+	}
+}
+
+func TestSearchByEmailNormalizesQuery(t *testing.T) {
+	ts := newTestServer(t, map[string]string{"EMAIL_CANONICALIZE_GMAIL": "true"})
+	token := ts.tokenFor(t, ts.newUser("viewer"))
+	ts.store.addUser(models.User{Username: "firstlast", DisplayName: "First Last", Email: "firstlast@gmail.com"})
+
+	tests := []struct {
+		name  string
+		q     string
+		found bool
+	}{
+		{"canonical", "firstlast@gmail.com", true},
+		{"case and whitespace", " FirstLast@Gmail.com ", true},
+		{"dots and tag", "first.last+news@gmail.com", true},
+		{"googlemail.com", "First.Last@googlemail.com", true},
+		{"another address", "first.last@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do(t, http.MethodGet, "/api/v1/users/search?by=email&q="+url.QueryEscape(tt.q), token, nil)
+			expectStatus(t, rec, http.StatusOK)
+			users := decode[models.ListResponse[models.UserPublic]](t, rec).Data
+			if found := len(users) == 1 && users[0].Username == "firstlast"; found != tt.found {
+				t.Fatalf("found = %v (%+v), want %v", found, users, tt.found)
+			}
+		})
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	store := &recordingStore{fakeStore: newFakeStore()}
+	server := NewServer(store, testConfig(t, map[string]string{"SEARCH_DEFAULT_LIMIT": "5", "SEARCH_MAX_LIMIT": "10"}))
+	ts := &testServer{Server: server, store: store.fakeStore, router: server.SetupRoutes()}
+	token := ts.tokenFor(t, ts.newUser("viewer"))
+
+	tests := []struct {
+		name   string
+		params string
+		want   int // limit passed to the store; 0 when the request is rejected
+	}{
+		{"default", "", 5},
+		{"empty", "&limit=", 5},
+		{"minimum", "&limit=1", 1},
+		{"maximum", "&limit=10", 10},
+		{"one over the maximum", "&limit=11", 0},
+		{"zero", "&limit=0", 0},
+		{"negative", "&limit=-1", 0},
+		{"not a number", "&limit=ten", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.limits = nil
+			path := "/api/v1/users/search?q=viewer" + tt.params
+			rec := ts.do(t, http.MethodGet, path, token, nil)
+			if tt.want == 0 {
+				expectError(t, rec, http.StatusBadRequest, "invalid_request")
+				if len(store.limits) != 0 {
+					t.Fatalf("rejected limit reached the store: %v", store.limits)
+				}
+				return
+			}
+			expectStatus(t, rec, http.StatusOK)
+			if len(store.limits) != 1 || store.limits[0] != tt.want {
+				t.Fatalf("store limits = %v, want [%d]", store.limits, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRespectsProfileVisibility(t *testing.T) {
+	ts := newTestServer(t, nil)
+	stranger, friend := ts.newUser("stranger"), ts.newUser("friend")
+	ts.store.addUser(models.User{Username: "limited", DisplayName: "limited", Email: "limited@example.com", ProfileVisibility: models.VisibilityConnectionsOnly})
+	ts.store.addUser(models.User{Username: "hidden", DisplayName: "hidden", Email: "hidden@example.com", ProfileVisibility: models.VisibilityPrivate})
+	for _, user := range ts.store.users {
+		if user.Username == "limited" || user.Username == "hidden" {
+			ts.store.addConnection(friend.ID, user.ID, models.StatusAccepted)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		viewer        *models.User
+		query         string
+		found         bool
+		withCreatedAt bool
+	}{
+		{"connections_only to a stranger", stranger, "limited", true, false},
+		{"connections_only to a connection", friend, "limited", true, true},
+		{"private to a stranger", stranger, "hidden", false, false},
+		{"private to a connection", friend, "hidden", true, true},
+	}
+
+	for _, tt := range tests {
+		token := ts.tokenFor(t, tt.viewer)
+		for _, by := range []string{"name", "email"} {
+			t.Run(by+"/"+tt.name, func(t *testing.T) {
+				q := tt.query
+				if by == "email" {
+					q += "@example.com"
+				}
+				rec := ts.do(t, http.MethodGet, "/api/v1/users/search?by="+by+"&q="+url.QueryEscape(q), token, nil)
+				expectStatus(t, rec, http.StatusOK)
+
+				users := decode[models.ListResponse[map[string]any]](t, rec).Data
+				if found := len(users) == 1; found != tt.found {
+					t.Fatalf("results = %v, want found = %v", users, tt.found)
+				}
+				if !tt.found {
+					return
+				}
+				if _, ok := users[0]["created_at"]; ok != tt.withCreatedAt {
+					t.Fatalf("result = %v, want created_at present = %v", users[0], tt.withCreatedAt)
+				}
+			})
+		}
+	}
+}
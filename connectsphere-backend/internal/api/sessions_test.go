@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"connectsphere-backend/internal/models"
+)
+
+// sessionID returns the ID of the session token is bound to
+func sessionID(t *testing.T, ts *testServer, token string) string {
+	t.Helper()
+
+	claims, err := ts.jwtManager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	return claims.ID
+}
+
+func TestListSessions(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob := ts.newUser("alice"), ts.newUser("bob")
+	laptop, phone := ts.tokenFor(t, alice), ts.tokenFor(t, alice)
+	ts.tokenFor(t, bob)
+
+	rec := ts.do(t, http.MethodGet, "/api/v1/users/me/sessions", laptop, nil)
+	expectStatus(t, rec, http.StatusOK)
+	sessions := decode[models.ListResponse[models.Session]](t, rec).Data
+	if len(sessions) != 2 {
+		t.Fatalf("sessions = %+v, want alice's two", sessions)
+	}
+	// Only the session making the request is marked current
+	for _, session := range sessions {
+		if want := session.ID.String() == sessionID(t, ts, laptop); session.Current != want {
+			t.Fatalf("session %s current = %v, want %v", session.ID, session.Current, want)
+		}
+	}
+
+	// Revoked sessions are no longer listed
+	expectStatus(t, ts.do(t, http.MethodDelete, "/api/v1/users/me/sessions/"+sessionID(t, ts, phone), laptop, nil), http.StatusOK)
+	rec = ts.do(t, http.MethodGet, "/api/v1/users/me/sessions", laptop, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if sessions := decode[models.ListResponse[models.Session]](t, rec).Data; len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("sessions after revoking = %+v, want only the current one", sessions)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob := ts.newUser("alice"), ts.newUser("bob")
+	laptop, phone := ts.tokenFor(t, alice), ts.tokenFor(t, alice)
+	bobToken := ts.tokenFor(t, bob)
+	phonePath := "/api/v1/users/me/sessions/" + sessionID(t, ts, phone)
+
+	// Another user's session can't be revoked, and is reported as not found
+	expectError(t, ts.do(t, http.MethodDelete, phonePath, bobToken, nil), http.StatusNotFound, "session_not_found")
+	expectStatus(t, ts.do(t, http.MethodGet, "/api/v1/users/me", phone, nil), http.StatusOK)
+
+	expectStatus(t, ts.do(t, http.MethodDelete, phonePath, laptop, nil), http.StatusOK)
+	expectError(t, ts.do(t, http.MethodDelete, phonePath, laptop, nil), http.StatusNotFound, "session_not_found")
+
+	// The revoked session's token is rejected by authMiddleware right away,
+	// while the other session keeps working
+	expectError(t, ts.do(t, http.MethodGet, "/api/v1/users/me", phone, nil), http.StatusUnauthorized, "unauthorized")
+	expectStatus(t, ts.do(t, http.MethodGet, "/api/v1/users/me", laptop, nil), http.StatusOK)
+
+	expectError(t, ts.do(t, http.MethodDelete, "/api/v1/users/me/sessions/not-a-uuid", laptop, nil), http.StatusBadRequest, "invalid_id")
+}
+
+func TestLogoutAll(t *testing.T) {
+	ts := newTestServer(t, nil)
+	alice, bob := ts.newUser("alice"), ts.newUser("bob")
+	laptop, phone := ts.tokenFor(t, alice), ts.tokenFor(t, alice)
+	bobToken := ts.tokenFor(t, bob)
+
+	rec := ts.do(t, http.MethodPost, "/api/v1/users/me/logout-all", laptop, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decode[struct{ Data map[string]int64 }](t, rec).Data["sessions_revoked"]; got != 2 {
+		t.Fatalf("sessions_revoked = %d, want 2", got)
+	}
+
+	// Every outstanding token is invalidated, including the one that logged out
+	for _, token := range []string{laptop, phone} {
+		expectError(t, ts.do(t, http.MethodGet, "/api/v1/users/me", token, nil), http.StatusUnauthorized, "unauthorized")
+	}
+	// Other users are unaffected, and a new login works again
+	expectStatus(t, ts.do(t, http.MethodGet, "/api/v1/users/me", bobToken, nil), http.StatusOK)
+	rec = ts.do(t, http.MethodGet, "/api/v1/users/me/sessions", ts.tokenFor(t, alice), nil)
+	expectStatus(t, rec, http.StatusOK)
+	if sessions := decode[models.ListResponse[models.Session]](t, rec).Data; len(sessions) != 1 {
+		t.Fatalf("sessions after logging in again = %+v, want only the new one", sessions)
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"connectsphere-backend/internal/auth"
+	"connectsphere-backend/internal/database"
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// unusablePassword is stored for accounts provisioned through SSO. It is not a
+// valid bcrypt hash, so password login can never succeed for them.
+const unusablePassword = "!"
+
+// ssoLogin exchanges a token from the trusted identity provider for a local
+// session token, provisioning the account on first sign-in
+func (s *Server) ssoLogin(c *gin.Context) {
+	var req models.SSOLoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	claims, err := s.jwtManager.ValidateExternalToken(req.Token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse(c, "invalid_token", "Invalid or expired identity provider token"))
+		return
+	}
+	if claims.Email == "" {
+		c.JSON(http.StatusBadRequest, errorResponse(c, "email_required", "The identity provider token has no email claim"))
+		return
+	}
+
+	username := externalUsername(claims.PreferredUsername, claims.Email)
+	displayName := models.NormalizeDisplayName(strings.TrimSpace(claims.Name))
+	if models.ValidateDisplayName(displayName) != nil {
+		displayName = username
+	}
+
+	newUser := &models.User{
+		ID:             uuid.New(),
+		Username:       username,
+		DisplayName:    displayName,
+		Email:          auth.NormalizeEmail(claims.Email, s.cfg.CanonicalizeGmail),
+		HashedPassword: unusablePassword,
+	}
+	user, err := s.db.GetOrCreateExternalUser(c.Request.Context(), claims.Issuer, claims.Subject, claims.EmailVerified, newUser, s.cfg.RegistrationMode, req.InviteCode)
+	for attempt := 1; attempt < maxProvisionAttempts && isNameConflict(err); attempt++ {
+		// The provider's names are only suggestions; fall back to a fresh username,
+		// which is also used as the display name
+		newUser.Username = externalUsername(claims.PreferredUsername, claims.Email)
+		newUser.DisplayName = newUser.Username
+		user, err = s.db.GetOrCreateExternalUser(c.Request.Context(), claims.Issuer, claims.Subject, claims.EmailVerified, newUser, s.cfg.RegistrationMode, req.InviteCode)
+	}
+	if err != nil {
+		if errors.Is(err, database.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, errorResponse(c, "email_taken", "An account with this email already exists and the identity provider has not verified the email"))
+			return
+		}
+		if errors.Is(err, database.ErrRegistrationClosed) {
+			c.JSON(http.StatusForbidden, errorResponse(c, "registration_closed", "Registration is closed"))
+			return
+		}
+		if errors.Is(err, database.ErrInvalidInviteCode) {
+			c.JSON(http.StatusForbidden, errorResponse(c, "invalid_invite_code", "A valid, unused invite code is required to register"))
+			return
+		}
+		if errors.Is(err, database.ErrDisplayNameTaken) {
+			displayNameTaken(c)
+			return
+		}
+		if errors.Is(err, database.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, errorResponse(c, "username_taken", "Username is already taken"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to sign in"))
+		return
+	}
+
+	expiry := s.cfg.TokenExpiry
+	if req.Remember {
+		expiry = s.cfg.RememberTokenExpiry
+	}
+
+	token, err := s.issueToken(c, user, expiry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(c, "internal_error", "Failed to generate token"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token: token,
+		User:  user.ToAuth(),
+	})
+}
+
+// maxProvisionAttempts bounds how often ssoLogin retries provisioning an account
+// with fallback names after a username or display name clash
+const maxProvisionAttempts = 3
+
+// isNameConflict reports whether provisioning failed on a username or display name in use
+func isNameConflict(err error) bool {
+	return errors.Is(err, database.ErrUsernameTaken) || errors.Is(err, database.ErrDisplayNameTaken)
+}
+
+// externalUsername derives a valid, very likely unique username for a provisioned
+// account from the provider's preferred username or the email's local part
+func externalUsername(preferred, email string) string {
+	base := preferred
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+
+	var b strings.Builder
+	for _, r := range base {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '_' {
+			b.WriteRune(r)
+		}
+		if b.Len() == 20 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("user")
+	}
+
+	// A random suffix avoids clashing with existing and reserved usernames
+	return b.String() + "_" + uuid.NewString()[:6]
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectsphere-backend/internal/auth"
+	"connectsphere-backend/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSSOIssuer = "https://idp.example.com"
+
+// testIdentityProvider serves a JWKS for one RSA key and signs tokens with it
+type testIdentityProvider struct {
+	key *rsa.PrivateKey
+	url string
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kid": "test",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return &testIdentityProvider{key: key, url: server.URL}
+}
+
+// token signs an identity provider token for subject with the given claims
+func (idp *testIdentityProvider) token(t *testing.T, subject string, claims auth.ExternalClaims) string {
+	t.Helper()
+
+	claims.Issuer = testSSOIssuer
+	claims.Subject = subject
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newSSOTestServer(t *testing.T, idp *testIdentityProvider, env map[string]string) *testServer {
+	t.Helper()
+
+	merged := map[string]string{
+		"SSO_ENABLED":  "true",
+		"SSO_JWKS_URL": idp.url,
+		"SSO_ISSUER":   testSSOIssuer,
+	}
+	for key, value := range env {
+		merged[key] = value
+	}
+	return newTestServer(t, merged)
+}
+
+func TestSSOLoginNameCollisions(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	ts := newSSOTestServer(t, idp, map[string]string{"UNIQUE_DISPLAY_NAMES": "true"})
+	ts.store.addUser(models.User{Username: "taken", DisplayName: "Alice Smith", Email: "alice@example.com"})
+
+	// The provider's display name is in use, so the generated username is used for both
+	rec := ts.do(t, http.MethodPost, "/api/v1/auth/sso", "", map[string]any{
+		"token": idp.token(t, "alice-2", auth.ExternalClaims{
+			Email:             "alice.smith@example.com",
+			Name:              "Alice Smith",
+			PreferredUsername: "alice",
+		}),
+	})
+	expectStatus(t, rec, http.StatusOK)
+	login := decode[models.LoginResponse](t, rec)
+	if !strings.HasPrefix(login.User.Username, "alice_") {
+		t.Fatalf("username = %q, want a generated alice_ username", login.User.Username)
+	}
+	if login.User.DisplayName != login.User.Username {
+		t.Fatalf("display name = %q, want the fallback %q", login.User.DisplayName, login.User.Username)
+	}
+
+	// Signing in again finds the same account
+	rec = ts.do(t, http.MethodPost, "/api/v1/auth/sso", "", map[string]any{
+		"token": idp.token(t, "alice-2", auth.ExternalClaims{Email: "alice.smith@example.com", Name: "Alice Smith"}),
+	})
+	expectStatus(t, rec, http.StatusOK)
+	if again := decode[models.LoginResponse](t, rec); again.User.ID != login.User.ID {
+		t.Fatalf("second sign-in got user %s, want %s", again.User.ID, login.User.ID)
+	}
+}
+
+func TestSSOLoginEmailTaken(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	ts := newSSOTestServer(t, idp, nil)
+	ts.newUser("bob")
+
+	rec := ts.do(t, http.MethodPost, "/api/v1/auth/sso", "", map[string]any{
+		"token": idp.token(t, "bob-1", auth.ExternalClaims{Email: "bob@example.com"}),
+	})
+	expectError(t, rec, http.StatusConflict, "email_taken")
+}
+
+func TestSSOLoginRegistrationMode(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	signIn := func(ts *testServer, subject, email, inviteCode string) *httptest.ResponseRecorder {
+		return ts.do(t, http.MethodPost, "/api/v1/auth/sso", "", map[string]any{
+			"token":       idp.token(t, subject, auth.ExternalClaims{Email: email, EmailVerified: true}),
+			"invite_code": inviteCode,
+		})
+	}
+
+	t.Run("closed", func(t *testing.T) {
+		ts := newSSOTestServer(t, idp, map[string]string{"REGISTRATION_MODE": "closed"})
+		ts.newUser("carol")
+
+		expectError(t, signIn(ts, "dave-1", "dave@example.com", ""), http.StatusForbidden, "registration_closed")
+		if _, err := ts.store.GetUserByEmail(context.Background(), "dave@example.com"); err == nil {
+			t.Fatal("an account was created while registration is closed")
+		}
+
+		// Existing accounts can still link and sign in
+		expectStatus(t, signIn(ts, "carol-1", "carol@example.com", ""), http.StatusOK)
+	})
+
+	t.Run("invite", func(t *testing.T) {
+		ts := newSSOTestServer(t, idp, map[string]string{"REGISTRATION_MODE": "invite"})
+		admin := ts.newUser("admin")
+		if _, err := ts.store.CreateInviteCode(context.Background(), "welcome", admin.ID, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		expectError(t, signIn(ts, "erin-1", "erin@example.com", ""), http.StatusForbidden, "invalid_invite_code")
+		expectError(t, signIn(ts, "erin-1", "erin@example.com", "wrong"), http.StatusForbidden, "invalid_invite_code")
+
+		expectStatus(t, signIn(ts, "erin-1", "erin@example.com", "welcome"), http.StatusOK)
+		if invite := ts.store.invites["welcome"]; invite.UsedAt == nil {
+			t.Fatal("invite code was not consumed")
+		}
+
+		// The code is single-use, and the linked account signs in without one
+		expectError(t, signIn(ts, "frank-1", "frank@example.com", "welcome"), http.StatusForbidden, "invalid_invite_code")
+		expectStatus(t, signIn(ts, "erin-1", "erin@example.com", ""), http.StatusOK)
+	})
+}
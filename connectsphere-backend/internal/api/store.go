@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"connectsphere-backend/internal/database"
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Store is the data access the API server depends on.
+// *database.DB implements it; tests can substitute an in-memory fake.
+type Store interface {
+	HealthCheck(ctx context.Context) error
+
+	// Users
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, []uuid.UUID, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	UpdatePasswordHash(ctx context.Context, id uuid.UUID, hashedPassword string) error
+	UpdateUser(ctx context.Context, id uuid.UUID, params database.UpdateUserParams) (*models.User, error)
+	SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit, offset int) ([]*models.User, error)
+	FindUserByEmail(ctx context.Context, viewerID uuid.UUID, email string) ([]*models.User, error)
+
+	// Display names
+	IsDisplayNameTaken(ctx context.Context, displayName string, excludeUserID uuid.UUID) (bool, error)
+
+	// External identities
+	GetOrCreateExternalUser(ctx context.Context, issuer, subject string, emailVerified bool, newUser *models.User, registrationMode, inviteCode string) (*models.User, error)
+
+	// Email changes
+	IsEmailTaken(ctx context.Context, email string, excludeUserID uuid.UUID) (bool, error)
+	CreateEmailChangeRequest(ctx context.Context, userID uuid.UUID, newEmail, tokenHash string, expiresAt time.Time) error
+	ConfirmEmailChange(ctx context.Context, tokenHash string) (*models.User, error)
+
+	// Connections
+	CreateConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.UserConnection, error)
+	CountConnectionRequestsSince(ctx context.Context, requesterID uuid.UUID, since time.Time) (int, *time.Time, error)
+	CountMutualConnections(ctx context.Context, userID, otherID uuid.UUID) (int, error)
+	GetConnectionByID(ctx context.Context, id uuid.UUID) (*models.UserConnection, error)
+	ConnectedUserIDs(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	AreConnected(ctx context.Context, userID, otherID uuid.UUID) (bool, error)
+	AcceptConnection(ctx context.Context, requesterID, addresseeID uuid.UUID, maxConnections int) (*models.UserConnection, error)
+	DeclineConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.UserConnection, error)
+	RemoveConnection(ctx context.Context, userID, friendID uuid.UUID) error
+	RemoveConnectionByID(ctx context.Context, connectionID, requestingUserID uuid.UUID) error
+	GetConnectionOverview(ctx context.Context, userID uuid.UUID, limit, offset int) (*models.ConnectionOverview, error)
+	GetConnectionCounts(ctx context.Context, userID uuid.UUID) (*models.ConnectionCounts, error)
+	ListConnectionEvents(ctx context.Context, userID, otherID uuid.UUID) ([]models.ConnectionEvent, error)
+	GetUserConnections(ctx context.Context, userID uuid.UUID, tag string) ([]models.ConnectionWithUser, error)
+	GetConnectionsOf(ctx context.Context, ownerID, viewerID uuid.UUID, limit, offset int) ([]models.UserLimited, int, error)
+	AddConnectionTag(ctx context.Context, userID, otherID uuid.UUID, tag string, maxPerConnection int) error
+	SetConnectionTags(ctx context.Context, userID, otherID uuid.UUID, tags []string) ([]string, error)
+	RemoveConnectionTag(ctx context.Context, userID, otherID uuid.UUID, tag string) error
+	ListConnectionTags(ctx context.Context, userID uuid.UUID) ([]models.ConnectionTagCount, error)
+	GetPendingConnectionRequests(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.ConnectionWithUser, error)
+	ExpirePendingConnections(ctx context.Context, cutoff time.Time) ([]models.UserConnection, error)
+
+	// Blocks
+	RelationshipState(ctx context.Context, userID, otherID uuid.UUID) (*models.Relationship, error)
+	RelationshipStatuses(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) (map[uuid.UUID]models.RelationshipStatus, error)
+	BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
+
+	// Admin
+	GetAdminStats(ctx context.Context) (*models.AdminStats, error)
+	ListDisplayNameHistory(ctx context.Context, userID uuid.UUID) ([]models.DisplayNameChange, error)
+	ListUsers(ctx context.Context, filter database.ListUsersFilter) ([]models.UserAuth, int, error)
+
+	// Invite codes
+	CreateInviteCode(ctx context.Context, code string, createdBy uuid.UUID, expiresAt *time.Time) (*models.InviteCode, error)
+	ListInviteCodes(ctx context.Context, limit, offset int) ([]models.InviteCode, int, error)
+	CreateUserWithInvite(ctx context.Context, user *models.User, code string) error
+
+	// Sessions
+	CreateSession(ctx context.Context, session *models.Session) error
+	IsSessionActive(ctx context.Context, id, userID uuid.UUID) (bool, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	RevokeSession(ctx context.Context, id, userID uuid.UUID) error
+	RevokeAllSessions(ctx context.Context, userID uuid.UUID) (int64, error)
+
+	// Notifications
+	CreateNotification(ctx context.Context, userID uuid.UUID, notificationType string, payload json.RawMessage) (*models.Notification, error)
+	ListNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]models.Notification, int, error)
+	MarkNotificationRead(ctx context.Context, userID, notificationID uuid.UUID) error
+	MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// Ensure the Postgres implementation satisfies Store
+var _ Store = (*database.DB)(nil)
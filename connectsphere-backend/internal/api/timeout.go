@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// untimedRoutes are long-lived streams that must not get a request deadline
+var untimedRoutes = map[string]bool{
+	"/api/v1/events": true,
+}
+
+// requestTimeout bounds how long a handler may run. The request context gets a
+// deadline (REQUEST_TIMEOUT, or the ROUTE_TIMEOUTS override for the matched route)
+// so in-flight database queries are cancelled; if the deadline passes before the
+// handler has responded, whatever it writes afterwards is discarded and the
+// client gets a 503 instead of a misleading 500.
+func (s *Server) requestTimeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if untimedRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		timeout := s.cfg.RequestTimeout
+		if override, ok := s.cfg.RouteTimeouts[c.FullPath()]; ok {
+			timeout = override
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		writer := &timeoutWriter{ResponseWriter: original, ctx: ctx}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+		if writer.timedOut || (!original.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, errorResponse(c, "timeout", "The request took too long to process"))
+		}
+	}
+}
+
+// timeoutWriter drops the handler's response once the request deadline has passed
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	timedOut bool
+}
+
+func (w *timeoutWriter) expired() bool {
+	if !w.timedOut && !w.ResponseWriter.Written() && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+	}
+	return w.timedOut
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if w.expired() {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.expired() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(data string) (int, error) {
+	if w.expired() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.WriteString(data)
+}
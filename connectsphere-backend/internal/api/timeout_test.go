@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// slowStore delays user lookups and searches by delay unless the request is
+// cancelled first, reporting how each call ended on done
+type slowStore struct {
+	*fakeStore
+	delay time.Duration
+	done  chan error
+}
+
+func (s *slowStore) wait(ctx context.Context) error {
+	var err error
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	s.done <- err
+	return err
+}
+
+func (s *slowStore) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.fakeStore.GetUserByID(ctx, id)
+}
+
+func (s *slowStore) SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit, offset int) ([]*models.User, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.fakeStore.SearchUsers(ctx, viewerID, query, limit, offset)
+}
+
+// newSlowTestServer returns a test server whose store takes delay to look up or search users
+func newSlowTestServer(t *testing.T, delay time.Duration, env map[string]string) (*testServer, *slowStore) {
+	t.Helper()
+
+	store := &slowStore{fakeStore: newFakeStore(), delay: delay, done: make(chan error, 1)}
+	server := NewServer(store, testConfig(t, env))
+	return &testServer{Server: server, store: store.fakeStore, router: server.SetupRoutes()}, store
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		// /users/me looks the user up, which outlasts REQUEST_TIMEOUT
+		{"slow handler times out", "/api/v1/users/me", http.StatusServiceUnavailable},
+		// Search has a longer ROUTE_TIMEOUTS override
+		{"route override allows slower handlers", "/api/v1/users/search?q=alice", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, store := newSlowTestServer(t, 500*time.Millisecond, map[string]string{
+				"REQUEST_TIMEOUT": "50ms",
+				"ROUTE_TIMEOUTS":  "/api/v1/users/search=5s",
+			})
+			token := ts.tokenFor(t, ts.newUser("alice"))
+
+			start := time.Now()
+			rec := ts.do(t, http.MethodGet, tt.path, token, nil)
+			elapsed := time.Since(start)
+
+			err := <-store.done
+			if tt.status == http.StatusServiceUnavailable {
+				expectError(t, rec, tt.status, "timeout")
+				// The store saw the deadline instead of running to completion
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("store call ended with %v, want context.DeadlineExceeded", err)
+				}
+				if elapsed >= store.delay {
+					t.Fatalf("client waited %v, longer than the slow call itself", elapsed)
+				}
+				return
+			}
+
+			expectStatus(t, rec, tt.status)
+			if err != nil {
+				t.Fatalf("store call was cancelled: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequestTimeoutSkipsEventStream(t *testing.T) {
+	for path := range untimedRoutes {
+		r := gin.New()
+		ts := newTestServer(t, map[string]string{"REQUEST_TIMEOUT": "1ms"})
+		var deadline bool
+		r.GET(path, ts.requestTimeout(), func(c *gin.Context) {
+			_, deadline = c.Request.Context().Deadline()
+			c.Status(http.StatusNoContent)
+		})
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if deadline {
+			t.Errorf("%s got a request deadline", path)
+		}
+	}
+}
+
+func TestTimeoutWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		expired bool
+		status  int
+		body    string
+	}{
+		{"writes before the deadline pass through", false, http.StatusCreated, "ok"},
+		{"writes after the deadline are dropped", true, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+			if tt.expired {
+				ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			}
+			defer cancel()
+
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			writer := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+
+			writer.WriteHeader(http.StatusCreated)
+			if _, err := writer.WriteString("ok"); err != nil {
+				t.Fatal(err)
+			}
+			writer.ResponseWriter.WriteHeaderNow()
+
+			if rec.Code != tt.status || rec.Body.String() != tt.body {
+				t.Fatalf("response = %d %q, want %d %q", rec.Code, rec.Body.String(), tt.status, tt.body)
+			}
+			if writer.timedOut != tt.expired {
+				t.Fatalf("timedOut = %v, want %v", writer.timedOut, tt.expired)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BuildInfo identifies the running build; the values are injected into main via -ldflags
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// SetBuildInfo sets the build details reported by GET /api/v1/version
+func (s *Server) SetBuildInfo(info BuildInfo) {
+	s.build = info
+}
+
+func (s *Server) version(c *gin.Context) {
+	c.JSON(http.StatusOK, s.build)
+}
@@ -1,61 +1,145 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrTokenExpired is returned by ValidateToken for a correctly signed token past its expiry
+var ErrTokenExpired = errors.New("token has expired")
+
+// ErrUnexpectedSigningMethod is returned for tokens not signed with the configured
+// algorithm, including unsigned ("none") tokens
+var ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
+
+// ErrExternalTokensDisabled is returned when validating an external token without a trusted issuer
+var ErrExternalTokensDisabled = errors.New("external tokens are not enabled")
+
 // JWTManager handles JWT token operations
 type JWTManager struct {
 	secretKey []byte
-	duration  time.Duration
+	method    *jwt.SigningMethodHMAC
+
+	// External identity provider whose RS256 tokens are accepted, if any
+	externalIssuer   string
+	externalAudience string
+	externalKeys     *JWKS
 }
 
-// NewJWTManager creates a new JWT manager
-func NewJWTManager(secretKey string, duration time.Duration) *JWTManager {
+// NewJWTManager creates a JWT manager that signs and accepts only the given HMAC
+// algorithm (HS256, HS384 or HS512); anything else falls back to HS256
+func NewJWTManager(secretKey, algorithm string) *JWTManager {
+	method, ok := jwt.GetSigningMethod(algorithm).(*jwt.SigningMethodHMAC)
+	if !ok {
+		method = jwt.SigningMethodHS256
+	}
+
 	return &JWTManager{
 		secretKey: []byte(secretKey),
-		duration:  duration,
+		method:    method,
 	}
 }
 
+// ClaimsVersion is stamped into new tokens as "ver" so the claim layout can change
+// later while tokens issued under an older layout are still recognised
+const ClaimsVersion = 1
+
+// TokenTypeAccess is the token_type of the tokens this server issues. Only access
+// tokens authenticate API requests, so a token of any other type, such as a refresh
+// token should one ever be issued, is rejected.
+const TokenTypeAccess = "access"
+
 // Claims represents the JWT claims
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Version   int       `json:"ver,omitempty"`
+	TokenType string    `json:"token_type,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for a user
-func (manager *JWTManager) GenerateToken(userID uuid.UUID, email string) (string, error) {
+// IsAccessToken reports whether the token may authenticate API requests. Tokens
+// issued before token types existed have none and were all access tokens.
+func (c *Claims) IsAccessToken() bool {
+	return c.TokenType == TokenTypeAccess || c.TokenType == ""
+}
+
+// GenerateToken generates a JWT access token for a user session that expires after duration.
+// The session ID is carried in the standard jti claim.
+func (manager *JWTManager) GenerateToken(userID uuid.UUID, email string, sessionID uuid.UUID, duration time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		Version:   ClaimsVersion,
+		TokenType: TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(manager.duration)),
+			ID:        sessionID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(manager.method, claims)
 	return token.SignedString(manager.secretKey)
 }
 
+// WellFormed is a cheap pre-check for a compact JWT: at most maxLength bytes (0 means
+// no cap) and three non-empty base64url segments. It lets callers reject junk
+// before any decoding or signature work.
+func WellFormed(tokenString string, maxLength int) bool {
+	if tokenString == "" || (maxLength > 0 && len(tokenString) > maxLength) {
+		return false
+	}
+
+	dots := 0
+	segmentLength := 0
+	for i := 0; i < len(tokenString); i++ {
+		switch ch := tokenString[i]; {
+		case ch == '.':
+			if segmentLength == 0 {
+				return false
+			}
+			dots++
+			segmentLength = 0
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9', ch == '-', ch == '_':
+			segmentLength++
+		default:
+			return false
+		}
+	}
+	return dots == 2 && segmentLength > 0
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func (manager *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&Claims{},
 		func(token *jwt.Token) (interface{}, error) {
+			// Only ever hand out the secret for the configured HMAC algorithm. This rejects
+			// alg "none" and stops an RS256 token "signed" with a public key as its HMAC
+			// secret from being checked against our secret.
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || token.Method.Alg() != manager.method.Alg() {
+				return nil, ErrUnexpectedSigningMethod
+			}
 			return manager.secretKey, nil
 		},
+		jwt.WithValidMethods([]string{manager.method.Alg()}),
 	)
 
 	if err != nil {
+		// The signature is verified before expiry, so an expired error means the
+		// token was genuine and the client only needs to refresh it
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
 		return nil, err
 	}
 
@@ -67,17 +151,73 @@ func (manager *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// ExternalClaims are the claims read from an identity provider's token
+type ExternalClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// TrustIssuer accepts RS256 tokens from issuer, verified against keys and, if
+// audience is non-empty, required to be addressed to it
+func (manager *JWTManager) TrustIssuer(issuer, audience string, keys *JWKS) {
+	manager.externalIssuer = issuer
+	manager.externalAudience = audience
+	manager.externalKeys = keys
+}
+
+// ValidateExternalToken validates a token issued by the trusted identity provider
+func (manager *JWTManager) ValidateExternalToken(tokenString string) (*ExternalClaims, error) {
+	if manager.externalKeys == nil {
+		return nil, ErrExternalTokensDisabled
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(manager.externalIssuer),
+	}
+	if manager.externalAudience != "" {
+		options = append(options, jwt.WithAudience(manager.externalAudience))
+	}
+
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&ExternalClaims{},
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return manager.externalKeys.Key(kid)
+		},
+		options...,
+	)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ExternalClaims)
+	// The parser only checks exp when present; provider tokens must always expire
+	if !ok || !token.Valid || claims.Subject == "" || claims.ExpiresAt == nil {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// GenerateVerificationToken returns a random URL-safe token and the hash to store for it.
+// Only the hash is persisted so a database leak does not expose usable tokens.
+func GenerateVerificationToken() (token, tokenHash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
 	}
-	return string(hashedBytes), nil
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
 }
 
-// CheckPassword checks if the provided password matches the hashed password
-func CheckPassword(hashedPassword, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
+// HashToken hashes a verification token for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
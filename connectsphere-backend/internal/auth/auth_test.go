@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret-that-is-long-enough-for-hs256"
+
+func TestGenerateTokenExpiry(t *testing.T) {
+	manager := NewJWTManager(testSecret, "HS256")
+	userID, sessionID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"default session", 24 * time.Hour},
+		{"remembered session", 30 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now().Truncate(time.Second)
+			token, err := manager.GenerateToken(userID, "alice@example.com", sessionID, tt.duration)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims, err := manager.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			if claims.UserID != userID || claims.ID != sessionID.String() {
+				t.Fatalf("claims = %+v, want user %s and session %s", claims, userID, sessionID)
+			}
+
+			// NumericDate has second precision
+			expiresAt := claims.ExpiresAt.Time
+			if earliest, latest := before.Add(tt.duration), time.Now().Add(tt.duration); expiresAt.Before(earliest) || expiresAt.After(latest) {
+				t.Fatalf("expires at %v, want between %v and %v", expiresAt, earliest, latest)
+			}
+		})
+	}
+}
+
+func TestValidateTokenExpiredVersusInvalid(t *testing.T) {
+	manager := NewJWTManager(testSecret, "HS256")
+	other := NewJWTManager("a-different-secret-that-is-long-enough", "HS256")
+
+	generate := func(m *JWTManager, duration time.Duration) string {
+		token, err := m.GenerateToken(uuid.New(), "alice@example.com", uuid.New(), duration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := generate(manager, time.Hour)
+
+	tests := []struct {
+		name    string
+		token   string
+		expired bool // want ErrTokenExpired rather than another error
+	}{
+		{"expired", generate(manager, -time.Minute), true},
+		{"wrong secret", generate(other, time.Hour), false},
+		// The signature is checked first, so a forged token never reads as merely expired
+		{"expired with the wrong secret", generate(other, -time.Minute), false},
+		{"tampered signature", valid[:len(valid)-2] + "xx", false},
+		{"malformed", "not.a.jwt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := manager.ValidateToken(tt.token)
+			if err == nil {
+				t.Fatal("ValidateToken accepted the token")
+			}
+			if got := errors.Is(err, ErrTokenExpired); got != tt.expired {
+				t.Fatalf("errors.Is(%v, ErrTokenExpired) = %v, want %v", err, got, tt.expired)
+			}
+		})
+	}
+}
+
+func TestValidateTokenRejectsOtherAlgorithms(t *testing.T) {
+	manager := NewJWTManager(testSecret, "HS256")
+	claims := Claims{
+		UserID:    uuid.New(),
+		Email:     "alice@example.com",
+		Version:   ClaimsVersion,
+		TokenType: TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: must(x509.MarshalPKIXPublicKey(&rsaKey.PublicKey))})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"alg none", must(jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType))},
+		{"RS256 signed with an attacker's key", must(jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(rsaKey))},
+		// Algorithm confusion: claims RS256 but is an HMAC over the public key, as a
+		// verifier that picked the key by the header's alg would check it
+		{"RS256 header with an HMAC over the public key", signWithHeaderAlg("RS256", claims, publicKeyPEM)},
+		{"RS256 header with an HMAC over the server secret", signWithHeaderAlg("RS256", claims, []byte(testSecret))},
+		{"another HMAC algorithm with the server secret", must(jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(testSecret)))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := manager.ValidateToken(tt.token)
+			if err == nil {
+				t.Fatalf("ValidateToken accepted the token: %+v", got)
+			}
+			if errors.Is(err, ErrTokenExpired) {
+				t.Fatalf("err = %v, want a rejection rather than expiry", err)
+			}
+		})
+	}
+
+	// The same claims signed with the configured algorithm are accepted
+	if _, err := manager.ValidateToken(must(jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret)))); err != nil {
+		t.Fatalf("HS256 token rejected: %v", err)
+	}
+}
+
+func TestNewJWTManagerAlgorithm(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"HS256", "HS256"},
+		{"HS512", "HS512"},
+		{"RS256", "HS256"}, // not an HMAC algorithm
+		{"none", "HS256"},
+		{"", "HS256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			if got := NewJWTManager(testSecret, tt.algorithm).method.Alg(); got != tt.want {
+				t.Fatalf("method = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWellFormed(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		maxLength int
+		want      bool
+	}{
+		{"three segments", "aaa.bbb.ccc", 0, true},
+		{"base64url alphabet", "aA0-_.bB1-_.cC2-_", 0, true},
+		{"at the length cap", "aaa.bbb.ccc", 11, true},
+		{"over the length cap", "aaa.bbb.cccc", 11, false},
+		{"empty", "", 0, false},
+		{"two segments", "aaa.bbb", 0, false},
+		{"four segments", "aaa.bbb.ccc.ddd", 0, false},
+		{"empty signature, as alg none produces", "aaa.bbb.", 0, false},
+		{"empty payload", "aaa..ccc", 0, false},
+		{"padding", "aaa=.bbb.ccc", 0, false},
+		{"standard base64 characters", "aa+a.bb/b.ccc", 0, false},
+		{"whitespace", "aaa.bbb.ccc ", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WellFormed(tt.token, tt.maxLength); got != tt.want {
+				t.Fatalf("WellFormed(%q, %d) = %v, want %v", tt.token, tt.maxLength, got, tt.want)
+			}
+		})
+	}
+}
+
+// signWithHeaderAlg builds a token whose header claims alg but whose signature is
+// an HMAC-SHA256 with key
+func signWithHeaderAlg(alg string, claims Claims, key []byte) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + alg + `","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString(must(json.Marshal(claims)))
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestIsAccessToken(t *testing.T) {
+	tests := []struct {
+		tokenType string
+		want      bool
+	}{
+		{TokenTypeAccess, true},
+		{"", true}, // issued before token types existed
+		{"refresh", false},
+		{"Access", false},
+		{"id", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tokenType, func(t *testing.T) {
+			claims := &Claims{TokenType: tt.tokenType}
+			if got := claims.IsAccessToken(); got != tt.want {
+				t.Fatalf("IsAccessToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import "strings"
+
+// gmailDomains receive mail for the same mailbox regardless of dots and +tags
+var gmailDomains = map[string]bool{"gmail.com": true, "googlemail.com": true}
+
+// NormalizeEmail returns the canonical form of an email address: trimmed and
+// lowercased, so "User@Example.com " and "user@example.com" are one account.
+// With canonicalizeGmail, Gmail addresses also lose dots and "+tag" suffixes in
+// the local part and googlemail.com becomes gmail.com, since all of those variants
+// reach the same inbox. Strings without an @ are only trimmed and lowercased.
+func NormalizeEmail(email string, canonicalizeGmail bool) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 || !canonicalizeGmail {
+		return email
+	}
+
+	local, domain := email[:at], email[at+1:]
+	if !gmailDomains[domain] {
+		return email
+	}
+
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	local = strings.ReplaceAll(local, ".", "")
+	if local == "" {
+		return email
+	}
+
+	return local + "@gmail.com"
+}
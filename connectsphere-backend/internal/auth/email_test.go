@@ -0,0 +1,35 @@
+package auth
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name              string
+		email             string
+		canonicalizeGmail bool
+		want              string
+	}{
+		{"already canonical", "user@example.com", false, "user@example.com"},
+		{"surrounding whitespace", "  user@example.com\t\n", false, "user@example.com"},
+		{"mixed case", "User@Example.COM", false, "user@example.com"},
+		{"dots and tags kept outside Gmail", "first.last+news@example.com", true, "first.last+news@example.com"},
+		{"Gmail left alone without the flag", "First.Last+news@Gmail.com", false, "first.last+news@gmail.com"},
+		{"Gmail dots", "first.last@gmail.com", true, "firstlast@gmail.com"},
+		{"Gmail tag", "firstlast+news@gmail.com", true, "firstlast@gmail.com"},
+		{"Gmail dots, tag and case", " First.Last+News@GMAIL.com ", true, "firstlast@gmail.com"},
+		{"googlemail.com", "first.last@googlemail.com", true, "firstlast@gmail.com"},
+		{"Gmail subdomain is not Gmail", "first.last@mail.gmail.com", true, "first.last@mail.gmail.com"},
+		{"nothing left of the local part", "+tag@gmail.com", true, "+tag@gmail.com"},
+		{"last @ separates the domain", `"a@b"@gmail.com`, true, `"a@b"@gmail.com`},
+		{"no @", " Not An Email ", true, "not an email"},
+		{"empty", "", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmail(tt.email, tt.canonicalizeGmail); got != tt.want {
+				t.Fatalf("NormalizeEmail(%q, %v) = %q, want %q", tt.email, tt.canonicalizeGmail, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms, selected with PASSWORD_HASH_ALGO. Each hash is
+// stored with its algorithm prefix ("$2a$"/"$2b$" for bcrypt, "$argon2id$" for
+// argon2id) so existing hashes keep verifying after the setting changes.
+const (
+	AlgoBcrypt   = "bcrypt"
+	AlgoArgon2id = "argon2id"
+)
+
+// argon2id parameters for new hashes (RFC 9106 second recommended option)
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// HashPassword hashes a password with the given algorithm
+func HashPassword(password, algo string) (string, error) {
+	switch algo {
+	case AlgoBcrypt:
+		hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashedBytes), nil
+	case AlgoArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	return "", fmt.Errorf("unknown password hash algorithm %q", algo)
+}
+
+// CheckPassword checks if the provided password matches the hashed password,
+// whichever supported algorithm produced the hash
+func CheckPassword(hashedPassword, password string) bool {
+	switch hashAlgorithm(hashedPassword) {
+	case AlgoBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
+	case AlgoArgon2id:
+		return checkArgon2id(hashedPassword, password)
+	}
+	return false
+}
+
+// NeedsRehash reports whether a hash was made with a different algorithm than
+// algo, so it should be replaced after the next successful login
+func NeedsRehash(hashedPassword, algo string) bool {
+	current := hashAlgorithm(hashedPassword)
+	return current != "" && current != algo
+}
+
+// hashAlgorithm identifies the algorithm from a stored hash's prefix
+func hashAlgorithm(hashedPassword string) string {
+	switch {
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		return AlgoArgon2id
+	case strings.HasPrefix(hashedPassword, "$2a$"), strings.HasPrefix(hashedPassword, "$2b$"), strings.HasPrefix(hashedPassword, "$2y$"):
+		return AlgoBcrypt
+	}
+	return ""
+}
+
+// checkArgon2id verifies a password against a "$argon2id$v=..$m=..,t=..,p=..$salt$key" hash
+func checkArgon2id(hashedPassword, password string) bool {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1
+}
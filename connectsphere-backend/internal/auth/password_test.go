@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPasswordAcrossAlgorithms(t *testing.T) {
+	// bcrypt only looks at the first 72 bytes; argon2id must not share that quirk
+	long := strings.Repeat("a", 72)
+
+	for _, algo := range []string{AlgoBcrypt, AlgoArgon2id} {
+		hashed, err := HashPassword("correct horse", algo)
+		if err != nil {
+			t.Fatalf("HashPassword(%s): %v", algo, err)
+		}
+		if got := hashAlgorithm(hashed); got != algo {
+			t.Fatalf("hash %q is recognised as %q, want %q", hashed, got, algo)
+		}
+
+		tests := []struct {
+			name     string
+			password string
+			want     bool
+		}{
+			{"right password", "correct horse", true},
+			{"wrong password", "correct horsf", false},
+			{"empty password", "", false},
+		}
+
+		for _, tt := range tests {
+			t.Run(algo+"/"+tt.name, func(t *testing.T) {
+				if got := CheckPassword(hashed, tt.password); got != tt.want {
+					t.Fatalf("CheckPassword = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+
+	t.Run("argon2id checks past 72 bytes", func(t *testing.T) {
+		hashed, err := HashPassword(long+"1", AlgoArgon2id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if CheckPassword(hashed, long+"2") {
+			t.Fatal("argon2id accepted a password differing after byte 72")
+		}
+	})
+
+	t.Run("unknown hash format", func(t *testing.T) {
+		if CheckPassword("plaintext", "plaintext") {
+			t.Fatal("CheckPassword accepted a hash without a known prefix")
+		}
+	})
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHash, err := HashPassword("password", AlgoBcrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := HashPassword("password", AlgoArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		algo string
+		want bool
+	}{
+		{"bcrypt hash, bcrypt configured", bcryptHash, AlgoBcrypt, false},
+		{"bcrypt hash, argon2id configured", bcryptHash, AlgoArgon2id, true},
+		{"argon2id hash, bcrypt configured", argonHash, AlgoBcrypt, true},
+		{"argon2id hash, argon2id configured", argonHash, AlgoArgon2id, false},
+		{"unrecognised hash is left alone", "plaintext", AlgoArgon2id, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRehash(tt.hash, tt.algo); got != tt.want {
+				t.Fatalf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,22 +15,216 @@ import (
 type Config struct {
 	DatabaseURL string
 	JWTSecret   string
+	Host        string // Interface to bind to; empty binds all interfaces
 	Port        string
 	GinMode     string
+
+	// TrustedProxies are the proxy IPs/CIDRs whose X-Forwarded-For is honoured; empty trusts none
+	TrustedProxies []string
+
+	// CORSAllowedMethods and CORSAllowedHeaders are advertised in CORS responses
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+	// CORSMaxAge is how many seconds browsers may cache a preflight response; 0 omits Access-Control-Max-Age
+	CORSMaxAge int
+
+	// Database pool tuning; zero values keep the pgxpool defaults
+	DBMaxConns        int
+	DBMinConns        int
+	DBMaxConnLifetime time.Duration
+	DBMaxConnIdleTime time.Duration
+	// DBSSLMode overrides the sslmode in DatabaseURL; empty keeps it (or defaults to prefer)
+	DBSSLMode string
+	// DBTraceSlowMS logs queries slower than this many milliseconds; 0 disables tracing
+	DBTraceSlowMS int
+	// DBQueryWarnThreshold logs a warning for requests running more queries than this; 0 disables the warning
+	DBQueryWarnThreshold int
+	// DBHealthCheckInterval is how often the background monitor pings the database
+	DBHealthCheckInterval time.Duration
+
+	// SSOEnabled accepts RS256 tokens from SSOIssuer, verified against the keys at SSOJWKSURL
+	SSOEnabled  bool
+	SSOJWKSURL  string
+	SSOIssuer   string
+	SSOAudience string // Optional; when set, external tokens must list it in aud
+	// SSOJWKSCacheTTL is how long fetched signing keys are cached
+	SSOJWKSCacheTTL time.Duration
+
+	// JWTAlgorithm is the only algorithm session tokens are signed and accepted with: HS256 (default), HS384 or HS512
+	JWTAlgorithm string
+	// JWTMaxLength is the longest bearer token accepted before it is parsed; 0 disables the cap
+	JWTMaxLength int
+
+	// IntrospectionSecret is the bearer credential internal services use for token
+	// introspection; empty disables the endpoint
+	IntrospectionSecret string
+
+	// PasswordHashAlgo is used for new password hashes: bcrypt (default) or argon2id.
+	// Older hashes still verify and are re-hashed on the user's next login.
+	PasswordHashAlgo string
+
+	// TokenExpiry is the lifetime of a regular session token
+	TokenExpiry time.Duration
+	// RememberTokenExpiry is the lifetime of a token issued with "remember me"
+	RememberTokenExpiry time.Duration
+	// EmailChangeTokenExpiry is how long an email change confirmation link stays valid
+	EmailChangeTokenExpiry time.Duration
+
+	// ConnectionRequestLimit caps requests a user can send per ConnectionRequestWindow; 0 disables the limit
+	ConnectionRequestLimit  int
+	ConnectionRequestWindow time.Duration
+	// ConnectionRequestExpiry cancels pending requests older than this; 0 or unset keeps them forever
+	ConnectionRequestExpiry time.Duration
+	// RateLimitWarningPercent adds X-RateLimit-Warning once this percentage of a limit or less remains; 0 disables it
+	RateLimitWarningPercent int
+	// DeclinedRequestCooldown is how long a requester must wait to ask again after being declined
+	DeclinedRequestCooldown time.Duration
+	// MaxConnections caps the accepted connections a user can have; 0 disables the cap
+	MaxConnections int
+
+	// RegistrationMode is open, invite (a single-use invite code is required) or closed
+	RegistrationMode string
+
+	// ReservedUsernames cannot be registered (compared case-insensitively)
+	ReservedUsernames []string
+	// UniqueDisplayNames requires display names to be unique (case-insensitive)
+	UniqueDisplayNames bool
+	// DisplayNameChangeCooldown is the minimum time between display name changes; 0 or unset disables it
+	DisplayNameChangeCooldown time.Duration
+	// CanonicalizeGmail strips dots and +tags from Gmail addresses so variants of one inbox can't register twice
+	CanonicalizeGmail bool
+
+	// SearchDefaultLimit is the page size for user search without ?limit; larger limits than SearchMaxLimit are rejected
+	SearchDefaultLimit int
+	SearchMaxLimit     int
+
+	// WebhookURL receives signed POSTs for WebhookEvents; empty disables webhooks
+	WebhookURL         string
+	WebhookSecret      string
+	WebhookEvents      []string
+	WebhookMaxAttempts int
+
+	// EventHistorySize is how many recent live events are kept per user for clients resuming with Last-Event-ID
+	EventHistorySize int
+	// MaxEventStreamsPerUser caps a user's concurrent live event streams; 0 disables the cap
+	MaxEventStreamsPerUser int
+	// EventSubscriberBuffer is how many events a stream may fall behind before it is disconnected
+	EventSubscriberBuffer int
+
+	// AdminStatsCacheTTL is how long GET /admin/stats results are reused
+	AdminStatsCacheTTL time.Duration
+
+	// ReadOnly starts the server in read-only maintenance mode; admins can change it at runtime
+	ReadOnly bool
+
+	// RequestTimeout bounds how long a handler may run before the client gets a 503
+	RequestTimeout time.Duration
+	// RouteTimeouts overrides RequestTimeout for specific route patterns (e.g. /api/v1/users/search)
+	RouteTimeouts map[string]time.Duration
+
+	// LogRedactHeaders are request headers whose values are masked in request logs
+	LogRedactHeaders []string
+	// LogRequestBodies enables logging request bodies for routes not matched by LogNoBodyRoutes
+	LogRequestBodies bool
+	// LogNoBodyRoutes are path patterns whose bodies are never logged; a trailing "*" matches by prefix
+	LogNoBodyRoutes []string
 }
 
-// Load loads configuration from environment variables
-func Load() *Config {
+// Load loads configuration from environment variables, falling back to the
+// values in configFile (if not empty) for variables that are not set
+func Load(configFile string) *Config {
 	// Load .env file if it exists (for local development)
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	if configFile != "" {
+		if err := loadFile(configFile); err != nil {
+			log.Fatalf("Failed to load config file %s: %v", configFile, err)
+		}
+	}
+
 	config := &Config{
 		DatabaseURL: getEnv("DATABASE_URL", ""),
 		JWTSecret:   getEnv("JWT_SECRET", ""),
+		Host:        getEnv("HOST", ""),
 		Port:        getEnv("PORT", "8080"),
 		GinMode:     getEnv("GIN_MODE", "debug"),
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", ""),
+
+		CORSAllowedMethods: getEnvList("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS"),
+		CORSAllowedHeaders: getEnvList("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,Accept-Language,If-Unmodified-Since,Last-Event-ID,X-Request-ID"),
+		CORSMaxAge:         getEnvInt("CORS_MAX_AGE", 600),
+
+		DBMaxConns:        getEnvInt("DB_MAX_CONNS", 0),
+		DBMinConns:        getEnvInt("DB_MIN_CONNS", 0),
+		DBMaxConnLifetime: getEnvDuration("DB_MAX_CONN_LIFETIME", 0),
+		DBMaxConnIdleTime: getEnvDuration("DB_MAX_CONN_IDLE_TIME", 0),
+
+		DBSSLMode: getEnv("DB_SSLMODE", ""),
+
+		DBTraceSlowMS:        getEnvInt("DB_TRACE_SLOW_MS", 0),
+		DBQueryWarnThreshold: getEnvInt("DB_QUERY_WARN_THRESHOLD", 0),
+
+		DBHealthCheckInterval: getEnvDuration("DB_HEALTH_CHECK_INTERVAL", 30*time.Second),
+
+		SSOEnabled:      getEnvBool("SSO_ENABLED", false),
+		SSOJWKSURL:      getEnv("SSO_JWKS_URL", ""),
+		SSOIssuer:       getEnv("SSO_ISSUER", ""),
+		SSOAudience:     getEnv("SSO_AUDIENCE", ""),
+		SSOJWKSCacheTTL: getEnvDuration("SSO_JWKS_CACHE_TTL", time.Hour),
+
+		JWTAlgorithm: getEnv("JWT_ALGORITHM", "HS256"),
+		JWTMaxLength: getEnvInt("JWT_MAX_LENGTH", 4096),
+
+		IntrospectionSecret: getEnv("INTROSPECTION_SECRET", ""),
+
+		PasswordHashAlgo: getEnv("PASSWORD_HASH_ALGO", "bcrypt"),
+
+		TokenExpiry:         getEnvDuration("TOKEN_EXPIRY", 24*time.Hour),
+		RememberTokenExpiry: getEnvDuration("REMEMBER_TOKEN_EXPIRY", 30*24*time.Hour),
+
+		EmailChangeTokenExpiry: getEnvDuration("EMAIL_CHANGE_TOKEN_EXPIRY", 24*time.Hour),
+
+		ConnectionRequestLimit:  getEnvInt("CONNECTION_REQUEST_LIMIT", 50),
+		ConnectionRequestWindow: getEnvDuration("CONNECTION_REQUEST_WINDOW", 24*time.Hour),
+		ConnectionRequestExpiry: getEnvNonNegativeDuration("CONNECTION_REQUEST_EXPIRY", 0),
+		RateLimitWarningPercent: getEnvInt("RATE_LIMIT_WARNING_PERCENT", 20),
+		DeclinedRequestCooldown: getEnvDuration("DECLINED_REQUEST_COOLDOWN", 7*24*time.Hour),
+		MaxConnections:          getEnvInt("MAX_CONNECTIONS", 5000),
+
+		RegistrationMode: getEnv("REGISTRATION_MODE", "open"),
+
+		ReservedUsernames:  getEnvList("RESERVED_USERNAMES", "admin,api,me,null"),
+		UniqueDisplayNames: getEnvBool("UNIQUE_DISPLAY_NAMES", false),
+		CanonicalizeGmail:  getEnvBool("EMAIL_CANONICALIZE_GMAIL", false),
+
+		DisplayNameChangeCooldown: getEnvNonNegativeDuration("DISPLAY_NAME_CHANGE_COOLDOWN", 0),
+
+		SearchDefaultLimit: getEnvInt("SEARCH_DEFAULT_LIMIT", 20),
+		SearchMaxLimit:     getEnvInt("SEARCH_MAX_LIMIT", 100),
+
+		WebhookURL:         getEnv("WEBHOOK_URL", ""),
+		WebhookSecret:      getEnv("WEBHOOK_SECRET", ""),
+		WebhookEvents:      getEnvList("WEBHOOK_EVENTS", "connection_request,connection_accepted"),
+		WebhookMaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+
+		EventHistorySize: getEnvInt("EVENT_HISTORY_SIZE", 100),
+
+		MaxEventStreamsPerUser: getEnvInt("MAX_EVENT_STREAMS_PER_USER", 5),
+		EventSubscriberBuffer:  getEnvInt("EVENT_SUBSCRIBER_BUFFER", 64),
+
+		AdminStatsCacheTTL: getEnvDuration("ADMIN_STATS_CACHE_TTL", time.Minute),
+
+		ReadOnly: getEnvBool("READ_ONLY", false),
+
+		RequestTimeout: getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+		RouteTimeouts:  getEnvDurationMap("ROUTE_TIMEOUTS", "/api/v1/users/search=30s"),
+
+		LogRedactHeaders: getEnvList("LOG_REDACT_HEADERS", "Authorization,Cookie,Set-Cookie"),
+		LogRequestBodies: getEnvBool("LOG_REQUEST_BODIES", false),
+		LogNoBodyRoutes:  getEnvList("LOG_NO_BODY_ROUTES", "/api/v1/auth/*,/api/v1/users/me/email"),
 	}
 
 	// Validate required environment variables
@@ -36,10 +234,57 @@ func Load() *Config {
 	if config.JWTSecret == "" {
 		log.Fatal("JWT_SECRET environment variable is required")
 	}
+	switch config.RegistrationMode {
+	case "open", "invite", "closed":
+	default:
+		log.Fatalf("REGISTRATION_MODE must be open, invite or closed, got %q", config.RegistrationMode)
+	}
+	switch config.JWTAlgorithm {
+	case "HS256", "HS384", "HS512":
+	default:
+		log.Fatalf("JWT_ALGORITHM must be HS256, HS384 or HS512, got %q", config.JWTAlgorithm)
+	}
+	if config.PasswordHashAlgo != "bcrypt" && config.PasswordHashAlgo != "argon2id" {
+		log.Fatalf("PASSWORD_HASH_ALGO must be bcrypt or argon2id, got %q", config.PasswordHashAlgo)
+	}
+	switch config.DBSSLMode {
+	case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		log.Fatalf("DB_SSLMODE must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q", config.DBSSLMode)
+	}
+	if config.RateLimitWarningPercent > 100 {
+		log.Fatalf("RATE_LIMIT_WARNING_PERCENT must be between 0 and 100, got %d", config.RateLimitWarningPercent)
+	}
+	if config.SearchDefaultLimit < 1 || config.SearchDefaultLimit > config.SearchMaxLimit {
+		log.Fatalf("SEARCH_DEFAULT_LIMIT must be between 1 and SEARCH_MAX_LIMIT (%d), got %d", config.SearchMaxLimit, config.SearchDefaultLimit)
+	}
+	if config.WebhookURL != "" && config.WebhookSecret == "" {
+		log.Fatal("WEBHOOK_SECRET is required when WEBHOOK_URL is set")
+	}
+	if config.SSOEnabled && (config.SSOJWKSURL == "" || config.SSOIssuer == "") {
+		log.Fatal("SSO_JWKS_URL and SSO_ISSUER are required when SSO_ENABLED is true")
+	}
+	if port, err := strconv.Atoi(config.Port); err != nil || port < 1 || port > 65535 {
+		log.Fatalf("PORT must be a number between 1 and 65535, got %q", config.Port)
+	}
+	if config.Host != "" && net.ParseIP(config.Host) == nil && config.Host != "localhost" {
+		log.Fatalf("HOST must be an IP address or localhost, got %q", config.Host)
+	}
 
 	return config
 }
 
+// CountDBQueries reports whether queries are counted per request: for the
+// DB_QUERY_WARN_THRESHOLD warning, or for the X-DB-Queries header in debug mode
+func (c *Config) CountDBQueries() bool {
+	return c.DBQueryWarnThreshold > 0 || c.GinMode == "debug"
+}
+
+// ListenAddr returns the host:port address the HTTP server binds to
+func (c *Config) ListenAddr() string {
+	return net.JoinHostPort(c.Host, c.Port)
+}
+
 // getEnv gets an environment variable with a fallback value
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
@@ -47,3 +292,84 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvInt parses an environment variable as a non-negative integer with a fallback value
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Fatalf("%s must be a non-negative integer, got %q", key, value)
+	}
+	return parsed
+}
+
+// getEnvBool parses an environment variable as a boolean with a fallback value
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("%s must be true or false, got %q", key, value)
+	}
+	return parsed
+}
+
+// getEnvDuration parses an environment variable as a time.Duration with a fallback value
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("%s must be a positive duration (e.g. 24h), got %q", key, value)
+	}
+	return duration
+}
+
+// getEnvNonNegativeDuration is getEnvDuration for settings where 0 means disabled
+func getEnvNonNegativeDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Fatalf("%s must be a duration of 0 (disabled) or more (e.g. 24h), got %q", key, value)
+	}
+	return duration
+}
+
+// getEnvDurationMap parses a comma-separated list of key=duration pairs
+func getEnvDurationMap(key, fallback string) map[string]time.Duration {
+	values := make(map[string]time.Duration)
+	for _, entry := range getEnvList(key, fallback) {
+		name, value, ok := strings.Cut(entry, "=")
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || duration <= 0 {
+			log.Fatalf("%s entries must look like /path=30s, got %q", key, entry)
+		}
+		values[strings.TrimSpace(name)] = duration
+	}
+	return values
+}
+
+// getEnvList splits a comma-separated environment variable, dropping empty entries
+func getEnvList(key, fallback string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, fallback), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetEnvNonNegativeDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"unset uses the fallback", "", time.Hour},
+		{"zero disables", "0", 0},
+		{"zero with a unit", "0s", 0},
+		{"positive", "36h", 36 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_DURATION", tt.value)
+			if got := getEnvNonNegativeDuration("TEST_DURATION", time.Hour); got != tt.want {
+				t.Fatalf("getEnvNonNegativeDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisabledDurationsAcceptZero(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("CONNECTION_REQUEST_EXPIRY", "0")
+	t.Setenv("DISPLAY_NAME_CHANGE_COOLDOWN", "0")
+
+	cfg := Load("")
+	if cfg.ConnectionRequestExpiry != 0 || cfg.DisplayNameChangeCooldown != 0 {
+		t.Fatalf("ConnectionRequestExpiry = %v, DisplayNameChangeCooldown = %v; want both 0", cfg.ConnectionRequestExpiry, cfg.DisplayNameChangeCooldown)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile reads a YAML (or JSON) file of environment variable names to values
+// and sets those not already in the environment, so env vars and .env override
+// the file. Lists become comma-separated values and maps become key=value pairs,
+// matching what getEnvList and getEnvDurationMap expect:
+//
+//	PORT: 8080
+//	RESERVED_USERNAMES: [admin, api, me, "null"]
+//	ROUTE_TIMEOUTS:
+//	  /api/v1/users/search: 30s
+func loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("invalid config file: %w", err)
+	}
+
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+
+		str, err := fileValue(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if err := os.Setenv(key, str); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileValue converts a decoded config file value to its environment variable form
+func fileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string, bool, int, float64:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			str, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = str
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			str, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+"="+str)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"connectsphere-backend/internal/models"
+)
+
+// Admin operations
+
+// ListUsersFilter narrows and orders the admin user listing.
+// Zero-valued time bounds are ignored.
+type ListUsersFilter struct {
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	SortBy        string // "created_at" or "username"
+	Descending    bool
+	Limit         int
+	Offset        int
+}
+
+// listUsersSortColumns whitelists the columns ListUsers can sort by
+var listUsersSortColumns = map[string]string{
+	"created_at": "created_at",
+	"username":   "username",
+}
+
+// ListUsers returns one page of users matching the filter together with the total match count
+func (db *DB) ListUsers(ctx context.Context, filter ListUsersFilter) ([]models.UserAuth, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	if !filter.CreatedAfter.IsZero() {
+		args = append(args, filter.CreatedAfter)
+		conditions = append(conditions, fmt.Sprintf("created_at > $%d", len(args)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		args = append(args, filter.CreatedBefore)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	sortColumn, ok := listUsersSortColumns[filter.SortBy]
+	if !ok {
+		sortColumn = "created_at"
+	}
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT `+userColumns+`, COUNT(*) OVER () AS total
+		FROM users
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT $%d OFFSET $%d`,
+		where, sortColumn, direction, direction, len(args)-1, len(args))
+
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.UserAuth, 0)
+	total := 0
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID, &user.Username, &user.DisplayName, &user.Email,
+			&user.HashedPassword, &user.ProfileVisibility, &user.DiscoverableByEmail, &user.ConnectionRequestPolicy,
+			&user.ShowConnections, &user.IsAdmin, &user.StatusMessage, &user.StatusExpiresAt, &user.CreatedAt, &user.UpdatedAt, &total,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user.ToAuth())
+	}
+
+	// The window count arrives with the rows, so a page past the end counts separately
+	if len(users) == 0 && filter.Offset > 0 {
+		if err := db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM users `+where, args[:len(args)-2]...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count users: %w", err)
+		}
+	}
+
+	return users, total, nil
+}
+
+// Admin stats cover this many recent days and weeks of signups
+const (
+	statsSignupDays  = 30
+	statsSignupWeeks = 12
+)
+
+// GetAdminStats computes user and connection totals, active users and recent
+// signups in two queries. Days and weeks are UTC; weeks start on Monday.
+// Periods without signups are included with a count of 0.
+func (db *DB) GetAdminStats(ctx context.Context) (*models.AdminStats, error) {
+	stats := &models.AdminStats{
+		SignupsPerDay:  make([]models.SignupCount, 0, statsSignupDays),
+		SignupsPerWeek: make([]models.SignupCount, 0, statsSignupWeeks),
+	}
+
+	totals := `
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM user_connections WHERE status = $1),
+			(SELECT COUNT(DISTINCT user_id) FROM sessions WHERE created_at > NOW() - INTERVAL '1 day'),
+			(SELECT COUNT(DISTINCT user_id) FROM sessions WHERE created_at > NOW() - INTERVAL '7 days'),
+			(SELECT COUNT(DISTINCT user_id) FROM sessions WHERE created_at > NOW() - INTERVAL '30 days')`
+
+	err := db.pool.QueryRow(ctx, totals, models.StatusAccepted).Scan(
+		&stats.TotalUsers, &stats.TotalConnections,
+		&stats.ActiveUsers.Day, &stats.ActiveUsers.Week, &stats.ActiveUsers.Month,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin stats: %w", err)
+	}
+
+	signups := `
+		WITH periods AS (
+			SELECT 'day' AS unit, generate_series(
+				date_trunc('day', NOW() AT TIME ZONE 'UTC') - ($1 - 1) * INTERVAL '1 day',
+				date_trunc('day', NOW() AT TIME ZONE 'UTC'),
+				INTERVAL '1 day') AS period_start
+			UNION ALL
+			SELECT 'week', generate_series(
+				date_trunc('week', NOW() AT TIME ZONE 'UTC') - ($2 - 1) * INTERVAL '1 week',
+				date_trunc('week', NOW() AT TIME ZONE 'UTC'),
+				INTERVAL '1 week')
+		)
+		SELECT p.unit, p.period_start AT TIME ZONE 'UTC', COUNT(u.id)
+		FROM periods p
+		LEFT JOIN users u
+			ON u.created_at >= p.period_start AT TIME ZONE 'UTC'
+			AND u.created_at < (p.period_start + CASE p.unit WHEN 'day' THEN INTERVAL '1 day' ELSE INTERVAL '1 week' END) AT TIME ZONE 'UTC'
+		GROUP BY p.unit, p.period_start
+		ORDER BY p.unit, p.period_start`
+
+	rows, err := db.pool.Query(ctx, signups, statsSignupDays, statsSignupWeeks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count signups: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var unit string
+		var count models.SignupCount
+		if err := rows.Scan(&unit, &count.Period, &count.Signups); err != nil {
+			return nil, fmt.Errorf("failed to scan signups: %w", err)
+		}
+		if unit == "day" {
+			stats.SignupsPerDay = append(stats.SignupsPerDay, count)
+		} else {
+			stats.SignupsPerWeek = append(stats.SignupsPerWeek, count)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count signups: %w", err)
+	}
+
+	return stats, nil
+}
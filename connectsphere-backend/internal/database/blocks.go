@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Block operations
+
+// BlockUser blocks a user and removes any connection or pending request
+// between the two users in the same transaction
+func (db *DB) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO blocked_users (blocker_id, blocked_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, blockerID, blockedID)
+		if err != nil {
+			return fmt.Errorf("failed to block user: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, `
+			DELETE FROM user_connections
+			WHERE (requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1)
+			RETURNING requester_id, addressee_id`,
+			blockerID, blockedID)
+		if err != nil {
+			return fmt.Errorf("failed to remove connection with blocked user: %w", err)
+		}
+		// Read every removed pair before logging: the connection is busy until rows is closed
+		var removed [][2]uuid.UUID
+		for rows.Next() {
+			var pair [2]uuid.UUID
+			if err := rows.Scan(&pair[0], &pair[1]); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan removed connection: %w", err)
+			}
+			removed = append(removed, pair)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to remove connection with blocked user: %w", err)
+		}
+
+		for _, pair := range removed {
+			if err := recordConnectionEvent(ctx, tx, pair[0], pair[1], blockerID, models.ConnectionEventRemoved); err != nil {
+				return err
+			}
+		}
+		if len(removed) > 0 {
+			if err := deleteConnectionTags(ctx, tx, blockerID, blockedID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UnblockUser removes a block previously placed by blockerID
+func (db *DB) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	query := `DELETE FROM blocked_users WHERE blocker_id = $1 AND blocked_id = $2`
+
+	result, err := db.pool.Exec(ctx, query, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("block not found")
+	}
+
+	return nil
+}
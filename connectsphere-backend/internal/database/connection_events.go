@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Connection event operations
+
+// recordConnectionEvent appends a status change to connection_events. It runs in
+// the transaction of the change itself, so the log can't miss or invent an event.
+func recordConnectionEvent(ctx context.Context, tx pgx.Tx, requesterID, addresseeID, actorID uuid.UUID, event string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO connection_events (requester_id, addressee_id, event, actor_id)
+		VALUES ($1, $2, $3, $4)`, requesterID, addresseeID, event, actorID)
+	if err != nil {
+		return fmt.Errorf("failed to record %s connection event: %w", event, err)
+	}
+	return nil
+}
+
+// ListConnectionEvents returns the status changes between two users in either
+// direction, oldest first
+func (db *DB) ListConnectionEvents(ctx context.Context, userID, otherID uuid.UUID) ([]models.ConnectionEvent, error) {
+	query := `
+		SELECT id, requester_id, addressee_id, event, actor_id, created_at
+		FROM connection_events
+		WHERE (requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1)
+		ORDER BY created_at, id`
+
+	rows, err := db.pool.Query(ctx, query, userID, otherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list connection events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.ConnectionEvent, 0)
+	for rows.Next() {
+		var event models.ConnectionEvent
+		err := rows.Scan(&event.ID, &event.RequesterID, &event.AddresseeID, &event.Event, &event.ActorID, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan connection event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrNotConnected is returned when tagging a user who is not an accepted connection
+	ErrNotConnected = errors.New("not connected")
+	// ErrTagLimitReached is returned when a connection already has the maximum number of tags
+	ErrTagLimitReached = errors.New("tag limit reached")
+	// ErrTagNotFound is returned when removing a tag the connection doesn't have
+	ErrTagNotFound = errors.New("tag not found")
+)
+
+// Connection tag operations
+
+// AddConnectionTag tags userID's connection with otherID. Tags are private to
+// userID. Adding a tag the connection already has is a no-op; a new tag beyond
+// maxPerConnection fails with ErrTagLimitReached.
+func (db *DB) AddConnectionTag(ctx context.Context, userID, otherID uuid.UUID, tag string, maxPerConnection int) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		// Lock the connection so concurrent adds can't both squeeze under the limit
+		var connectionID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			SELECT id FROM user_connections
+			WHERE ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+			AND status = $3
+			FOR UPDATE`, userID, otherID, models.StatusAccepted).Scan(&connectionID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrNotConnected
+			}
+			return fmt.Errorf("failed to add connection tag: %w", err)
+		}
+
+		var count int
+		var exists bool
+		err = tx.QueryRow(ctx, `
+			SELECT COUNT(*), COALESCE(BOOL_OR(tag = $3), false)
+			FROM connection_tags
+			WHERE user_id = $1 AND connection_user_id = $2`, userID, otherID, tag).Scan(&count, &exists)
+		if err != nil {
+			return fmt.Errorf("failed to count connection tags: %w", err)
+		}
+		if exists {
+			return nil
+		}
+		if count >= maxPerConnection {
+			return ErrTagLimitReached
+		}
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO connection_tags (user_id, connection_user_id, tag)
+			VALUES ($1, $2, $3)`, userID, otherID, tag); err != nil {
+			return fmt.Errorf("failed to add connection tag: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetConnectionTags replaces all of userID's tags on their connection with otherID
+// with tags in one transaction, returning the new set sorted. Callers validate and
+// deduplicate tags and enforce the per-connection limit.
+func (db *DB) SetConnectionTags(ctx context.Context, userID, otherID uuid.UUID, tags []string) ([]string, error) {
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		var connectionID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			SELECT id FROM user_connections
+			WHERE ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+			AND status = $3
+			FOR UPDATE`, userID, otherID, models.StatusAccepted).Scan(&connectionID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrNotConnected
+			}
+			return fmt.Errorf("failed to set connection tags: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM connection_tags WHERE user_id = $1 AND connection_user_id = $2`, userID, otherID); err != nil {
+			return fmt.Errorf("failed to clear connection tags: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO connection_tags (user_id, connection_user_id, tag)
+			SELECT $1, $2, tag FROM unnest($3::text[]) AS tag`, userID, otherID, tags); err != nil {
+			return fmt.Errorf("failed to set connection tags: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A copy rather than append, which would return nil for an empty set and
+	// serialize as null instead of []
+	sorted := make([]string, len(tags))
+	copy(sorted, tags)
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// RemoveConnectionTag removes one of userID's tags from their connection with otherID
+func (db *DB) RemoveConnectionTag(ctx context.Context, userID, otherID uuid.UUID, tag string) error {
+	query := `DELETE FROM connection_tags WHERE user_id = $1 AND connection_user_id = $2 AND tag = $3`
+
+	result, err := db.pool.Exec(ctx, query, userID, otherID, tag)
+	if err != nil {
+		return fmt.Errorf("failed to remove connection tag: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+// ListConnectionTags returns the tags userID uses and how many connections have each, by name
+func (db *DB) ListConnectionTags(ctx context.Context, userID uuid.UUID) ([]models.ConnectionTagCount, error) {
+	query := `
+		SELECT tag, COUNT(*)
+		FROM connection_tags
+		WHERE user_id = $1
+		GROUP BY tag
+		ORDER BY tag`
+
+	rows, err := db.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list connection tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]models.ConnectionTagCount, 0)
+	for rows.Next() {
+		var tag models.ConnectionTagCount
+		if err := rows.Scan(&tag.Tag, &tag.Connections); err != nil {
+			return nil, fmt.Errorf("failed to scan connection tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// deleteConnectionTags drops both users' tags on their connection when it ends,
+// so they don't reappear if the two connect again
+func deleteConnectionTags(ctx context.Context, tx pgx.Tx, userID, otherID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		DELETE FROM connection_tags
+		WHERE (user_id = $1 AND connection_user_id = $2) OR (user_id = $2 AND connection_user_id = $1)`,
+		userID, otherID)
+	if err != nil {
+		return fmt.Errorf("failed to delete connection tags: %w", err)
+	}
+	return nil
+}
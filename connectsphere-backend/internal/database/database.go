@@ -2,13 +2,17 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"connectsphere-backend/internal/models"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,13 +21,56 @@ type DB struct {
 	pool *pgxpool.Pool
 }
 
+// PoolOptions tunes the connection pool. Zero values keep the pgxpool defaults.
+type PoolOptions struct {
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+	// SSLMode overrides the URL's sslmode; when both are empty defaultSSLMode is used
+	SSLMode string
+	// SlowQueryThreshold logs queries that run at least this long; zero disables slow query logging
+	SlowQueryThreshold time.Duration
+	// CountQueries enables per-request query counting through WithQueryCounter
+	CountQueries bool
+}
+
 // New creates a new database connection
-func New(databaseURL string) (*DB, error) {
+func New(databaseURL string, opts PoolOptions) (*DB, error) {
+	databaseURL, sslMode, err := withSSLMode(databaseURL, opts.SSLMode)
+	if err != nil {
+		return nil, err
+	}
+
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database URL: %w", err)
 	}
 
+	if opts.MaxConns > 0 {
+		config.MaxConns = opts.MaxConns
+	}
+	if opts.MinConns > 0 {
+		config.MinConns = opts.MinConns
+	}
+	if opts.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = opts.MaxConnLifetime
+	}
+	if opts.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.SlowQueryThreshold > 0 || opts.CountQueries {
+		config.ConnConfig.Tracer = &queryTracer{threshold: opts.SlowQueryThreshold}
+	}
+
+	// Drop connections the server closed (e.g. after a Postgres restart) instead of handing them out
+	config.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+		return !conn.IsClosed()
+	}
+
+	log.Printf("Database pool: max_conns=%d min_conns=%d max_conn_lifetime=%s max_conn_idle_time=%s sslmode=%s",
+		config.MaxConns, config.MinConns, config.MaxConnLifetime, config.MaxConnIdleTime, sslMode)
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -31,7 +78,7 @@ func New(databaseURL string) (*DB, error) {
 
 	// Test the connection
 	if err := pool.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, fmt.Errorf("failed to ping database: %s: %w", describeConnectError(err, sslMode), err)
 	}
 
 	log.Println("Successfully connected to database")
@@ -46,35 +93,90 @@ func (db *DB) Close() {
 
 // User operations
 
+// visibleToViewer returns a users-table condition that hides private profiles
+// from everyone except the viewer (bound to viewerParam) and their connections
+func visibleToViewer(viewerParam string) string {
+	return `(users.profile_visibility <> 'private' OR users.id = ` + viewerParam + ` OR EXISTS (
+		SELECT 1 FROM user_connections uc
+		WHERE uc.status = 'accepted'
+		  AND ((uc.requester_id = ` + viewerParam + ` AND uc.addressee_id = users.id)
+		    OR (uc.requester_id = users.id AND uc.addressee_id = ` + viewerParam + `))
+	))`
+}
+
+// notBlocked returns a users-table condition excluding users in a block
+// relationship, in either direction, with the viewer (bound to viewerParam)
+func notBlocked(viewerParam string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM blocked_users b
+		WHERE (b.blocker_id = ` + viewerParam + ` AND b.blocked_id = users.id)
+		   OR (b.blocker_id = users.id AND b.blocked_id = ` + viewerParam + `)
+	)`
+}
+
+// userColumns lists the users columns in the order scanUser expects them
+const userColumns = `id, username, display_name, email, hashed_password, profile_visibility, discoverable_by_email, connection_request_policy, show_connections, is_admin, status_message, status_expires_at, created_at, updated_at`
+
+// scanUser scans a row selected with userColumns into a User
+func scanUser(row pgx.Row) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID, &user.Username, &user.DisplayName, &user.Email,
+		&user.HashedPassword, &user.ProfileVisibility, &user.DiscoverableByEmail, &user.ConnectionRequestPolicy,
+		&user.ShowConnections, &user.IsAdmin, &user.StatusMessage, &user.StatusExpiresAt, &user.CreatedAt, &user.UpdatedAt,
+	)
+	return user, err
+}
+
 // CreateUser creates a new user in the database
 func (db *DB) CreateUser(ctx context.Context, user *models.User) error {
+	return insertUser(ctx, db.pool, user)
+}
+
+// rowQuerier is satisfied by both the pool and a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// ErrUsernameTaken is returned when creating a user whose username is already in use
+var ErrUsernameTaken = errors.New("username already in use")
+
+// isUsernameConflict reports whether err is a unique violation on the username
+func isUsernameConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" &&
+		(pgErr.ConstraintName == "users_username_key" || pgErr.ConstraintName == "idx_users_username_lower")
+}
+
+// insertUser inserts user and fills in its database defaults
+func insertUser(ctx context.Context, q rowQuerier, user *models.User) error {
 	query := `
 		INSERT INTO users (id, username, display_name, email, hashed_password)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING created_at, updated_at`
+		RETURNING profile_visibility, discoverable_by_email, connection_request_policy, show_connections, created_at, updated_at`
 
-	err := db.pool.QueryRow(ctx, query,
+	err := q.QueryRow(ctx, query,
 		user.ID, user.Username, user.DisplayName, user.Email, user.HashedPassword,
-	).Scan(&user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ProfileVisibility, &user.DiscoverableByEmail, &user.ConnectionRequestPolicy, &user.ShowConnections, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
+		if isDisplayNameConflict(err) {
+			return ErrDisplayNameTaken
+		}
+		if isUsernameConflict(err) {
+			return ErrUsernameTaken
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return nil
 }
 
-// GetUserByEmail retrieves a user by email
+// GetUserByEmail retrieves a user by email (case-insensitive)
 func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	user := &models.User{}
-	query := `
-		SELECT id, username, display_name, email, hashed_password, created_at, updated_at
-		FROM users WHERE email = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE LOWER(email) = LOWER($1)`
 
-	err := db.pool.QueryRow(ctx, query, email).Scan(
-		&user.ID, &user.Username, &user.DisplayName, &user.Email,
-		&user.HashedPassword, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := scanUser(db.pool.QueryRow(ctx, query, email))
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -88,15 +190,9 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, e
 
 // GetUserByID retrieves a user by ID
 func (db *DB) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-	user := &models.User{}
-	query := `
-		SELECT id, username, display_name, email, hashed_password, created_at, updated_at
-		FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
-	err := db.pool.QueryRow(ctx, query, id).Scan(
-		&user.ID, &user.Username, &user.DisplayName, &user.Email,
-		&user.HashedPassword, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := scanUser(db.pool.QueryRow(ctx, query, id))
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -108,17 +204,54 @@ func (db *DB) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, erro
 	return user, nil
 }
 
-// GetUserByUsername retrieves a user by username
+// GetUsersByIDs retrieves the users with the given IDs in the order requested, listing
+// each ID once. IDs that match no user are returned in notFound, also in order.
+func (db *DB) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) (users []*models.User, notFound []uuid.UUID, err error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ANY($1)`
+
+	rows, err := db.pool.Query(ctx, query, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get users by IDs: %w", err)
+	}
+	defer rows.Close()
+
+	// ANY doesn't preserve order, so match the rows back up with the request
+	byID := make(map[uuid.UUID]*models.User, len(ids))
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		byID[user.ID] = user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to get users by IDs: %w", err)
+	}
+
+	users = make([]*models.User, 0, len(byID))
+	notFound = make([]uuid.UUID, 0)
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if user, ok := byID[id]; ok {
+			users = append(users, user)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+
+	return users, notFound, nil
+}
+
+// GetUserByUsername retrieves a user by username (case-insensitive)
 func (db *DB) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
-	user := &models.User{}
-	query := `
-		SELECT id, username, display_name, email, hashed_password, created_at, updated_at
-		FROM users WHERE username = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE LOWER(username) = LOWER($1)`
 
-	err := db.pool.QueryRow(ctx, query, username).Scan(
-		&user.ID, &user.Username, &user.DisplayName, &user.Email,
-		&user.HashedPassword, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := scanUser(db.pool.QueryRow(ctx, query, username))
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -130,60 +263,170 @@ func (db *DB) GetUserByUsername(ctx context.Context, username string) (*models.U
 	return user, nil
 }
 
-// UpdateUser updates a user's profile
-func (db *DB) UpdateUser(ctx context.Context, id uuid.UUID, displayName string) error {
+// ErrProfileModified is returned by UpdateUser when the profile changed since the
+// version the caller expected
+var ErrProfileModified = errors.New("profile was modified concurrently")
+
+// UpdateUserParams are the profile changes UpdateUser applies; nil fields are left unchanged
+type UpdateUserParams struct {
+	DisplayName             *string
+	ProfileVisibility       *string
+	DiscoverableByEmail     *bool
+	ConnectionRequestPolicy *string
+	ShowConnections         *bool
+	StatusMessage           *string // "" clears the status
+	StatusExpiresAt         *time.Time
+
+	// ExpectedUpdatedAt, if set, must equal the profile's current updated_at
+	ExpectedUpdatedAt *time.Time
+	// UnmodifiedSince, if set, must not be older than updated_at; it has second precision
+	UnmodifiedSince *time.Time
+	// DisplayNameCooldown is the minimum time between display name changes
+	DisplayNameCooldown time.Duration
+}
+
+// UpdateUser applies the provided profile fields and returns the updated user.
+// A display name change is recorded in the history and refused with a
+// *DisplayNameCooldownError if the previous change was less than
+// params.DisplayNameCooldown ago.
+func (db *DB) UpdateUser(ctx context.Context, id uuid.UUID, params UpdateUserParams) (*models.User, error) {
 	query := `
 		UPDATE users 
-		SET display_name = $1, updated_at = NOW()
-		WHERE id = $2`
+		SET display_name = COALESCE($1, display_name),
+		    profile_visibility = COALESCE($2, profile_visibility),
+		    discoverable_by_email = COALESCE($3, discoverable_by_email),
+		    connection_request_policy = COALESCE($4, connection_request_policy),
+		    show_connections = COALESCE($10, show_connections),
+		    -- A provided status replaces both status columns; "" clears the status
+		    status_message = CASE WHEN $8::text IS NULL THEN status_message ELSE NULLIF($8, '') END,
+		    status_expires_at = CASE WHEN $8::text IS NULL THEN status_expires_at ELSE $9 END,
+		    updated_at = NOW()
+		WHERE id = $5
+		  AND ($6::timestamptz IS NULL OR updated_at = $6)
+		  AND ($7::timestamptz IS NULL OR updated_at < $7 + INTERVAL '1 second')
+		RETURNING ` + userColumns
+
+	var user *models.User
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		if params.DisplayName != nil {
+			if err := recordDisplayNameChange(ctx, tx, id, *params.DisplayName, params.DisplayNameCooldown); err != nil {
+				return err
+			}
+		}
 
-	result, err := db.pool.Exec(ctx, query, displayName, id)
+		var err error
+		user, err = scanUser(tx.QueryRow(ctx, query,
+			params.DisplayName, params.ProfileVisibility, params.DiscoverableByEmail, params.ConnectionRequestPolicy, id,
+			params.ExpectedUpdatedAt, params.UnmodifiedSince, params.StatusMessage, params.StatusExpiresAt, params.ShowConnections,
+		))
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				if params.ExpectedUpdatedAt != nil || params.UnmodifiedSince != nil {
+					return ErrProfileModified
+				}
+				return fmt.Errorf("user not found")
+			}
+			if isDisplayNameConflict(err) {
+				return ErrDisplayNameTaken
+			}
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+		return nil, err
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+	return user, nil
+}
+
+// UpdatePasswordHash replaces a user's stored password hash
+func (db *DB) UpdatePasswordHash(ctx context.Context, id uuid.UUID, hashedPassword string) error {
+	query := `UPDATE users SET hashed_password = $1 WHERE id = $2`
+
+	if _, err := db.pool.Exec(ctx, query, hashedPassword, id); err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
 	}
 
 	return nil
 }
 
-// SearchUsers searches for users by username or display name with improved matching
-func (db *DB) SearchUsers(ctx context.Context, query string, limit int) ([]models.UserPublic, error) {
+// SearchUsers searches for users by username or display name with improved matching.
+// Private profiles are only returned to the viewer's own connections, and users
+// in a block relationship with the viewer are never returned. Callers pick which
+// fields of each user the viewer may see.
+func (db *DB) SearchUsers(ctx context.Context, viewerID uuid.UUID, query string, limit, offset int) ([]*models.User, error) {
+	// Every word of the query must appear in the username or display name, so
+	// "john doe" finds "John Doe" and "doe_john". The whole query still drives the ranking.
+	tokens := strings.Fields(query)
+
 	// Enhanced search query with better ranking and matching
 	searchQuery := `
-		SELECT id, username, display_name, created_at,
-		       -- Ranking system: exact matches first, then prefix matches, then partial matches
-		       CASE 
-		           WHEN LOWER(username) = LOWER($1) OR LOWER(display_name) = LOWER($1) THEN 1
-		           WHEN LOWER(username) LIKE LOWER($1) || '%' OR LOWER(display_name) LIKE LOWER($1) || '%' THEN 2
-		           WHEN LOWER(username) LIKE '%' || LOWER($1) || '%' OR LOWER(display_name) LIKE '%' || LOWER($1) || '%' THEN 3
-		           ELSE 4
-		       END as rank
+		SELECT ` + userColumns + `
 		FROM users 
-		WHERE LOWER(username) LIKE '%' || LOWER($1) || '%' 
-		   OR LOWER(display_name) LIKE '%' || LOWER($1) || '%'
-		ORDER BY rank ASC, 
+		WHERE NOT EXISTS (
+		      SELECT 1 FROM unnest($5::text[]) AS token
+		      WHERE LOWER(username) NOT LIKE '%' || LOWER(token) || '%'
+		        AND LOWER(display_name) NOT LIKE '%' || LOWER(token) || '%'
+		  )
+		  AND ` + visibleToViewer("$4") + `
+		  AND ` + notBlocked("$4") + `
+		ORDER BY
+		         -- Ranking system: exact matches first, then prefix matches, then partial matches
+		         CASE 
+		             WHEN LOWER(username) = LOWER($1) OR LOWER(display_name) = LOWER($1) THEN 1
+		             WHEN LOWER(username) LIKE LOWER($1) || '%' OR LOWER(display_name) LIKE LOWER($1) || '%' THEN 2
+		             WHEN LOWER(username) LIKE '%' || LOWER($1) || '%' OR LOWER(display_name) LIKE '%' || LOWER($1) || '%' THEN 3
+		             ELSE 4
+		         END ASC, 
 		         -- Secondary ordering: exact matches first, then by length (shorter names first), then alphabetically
 		         CASE WHEN LOWER(username) = LOWER($1) THEN 0 ELSE 1 END,
 		         CASE WHEN LOWER(display_name) = LOWER($1) THEN 0 ELSE 1 END,
 		         LENGTH(username), 
 		         LENGTH(display_name),
-		         username
-		LIMIT $2`
+		         username,
+		         -- Unique final key: offset pages must see one fixed order or rows repeat or vanish between pages
+		         id
+		LIMIT $2 OFFSET $3`
 
-	rows, err := db.pool.Query(ctx, searchQuery, query, limit)
+	rows, err := db.pool.Query(ctx, searchQuery, query, limit, offset, viewerID, tokens)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []models.UserPublic
+	users := make([]*models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// FindUserByEmail looks up a user by exact, case-insensitive email for the viewer.
+// Users who opted out of email discovery or are private to the viewer are not returned.
+func (db *DB) FindUserByEmail(ctx context.Context, viewerID uuid.UUID, email string) ([]*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE LOWER(email) = LOWER($1)
+		  AND discoverable_by_email
+		  AND ` + visibleToViewer("$2") + `
+		  AND ` + notBlocked("$2")
+
+	rows, err := db.pool.Query(ctx, query, email, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user by email: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*models.User, 0, 1)
 	for rows.Next() {
-		var user models.UserPublic
-		var rank int // We don't need to return this, just for the query
-		err := rows.Scan(&user.ID, &user.Username, &user.DisplayName, &user.CreatedAt, &rank)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -195,32 +438,84 @@ func (db *DB) SearchUsers(ctx context.Context, query string, limit int) ([]model
 
 // Connection operations
 
-// CreateConnection creates a new connection request
-func (db *DB) CreateConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) error {
-	query := `
-		INSERT INTO user_connections (requester_id, addressee_id, status)
-		VALUES ($1, $2, $3)`
+// connectionColumns lists the user_connections columns in the order scanConnection expects them
+const connectionColumns = `id, requester_id, addressee_id, status, created_at, updated_at`
+
+// scanConnection scans a row selected with connectionColumns into a UserConnection
+func scanConnection(row pgx.Row) (*models.UserConnection, error) {
+	connection := &models.UserConnection{}
+	err := row.Scan(
+		&connection.ID, &connection.RequesterID, &connection.AddresseeID,
+		&connection.Status, &connection.CreatedAt, &connection.UpdatedAt,
+	)
+	return connection, err
+}
+
+// CreateConnection creates a new connection request and returns the created row.
+// A previously declined request in either direction is replaced by the new one.
+// Every request is also logged so rate limits survive declines and removals.
+func (db *DB) CreateConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.UserConnection, error) {
+	var connection *models.UserConnection
+
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			DELETE FROM user_connections
+			WHERE requester_id = $1 AND addressee_id = $2 AND status = $3`,
+			addresseeID, requesterID, models.StatusDeclined)
+		if err != nil {
+			return err
+		}
+
+		connection, err = scanConnection(tx.QueryRow(ctx, `
+			INSERT INTO user_connections (requester_id, addressee_id, status)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (requester_id, addressee_id) DO UPDATE
+			SET status = EXCLUDED.status, created_at = NOW(), updated_at = NOW()
+			WHERE user_connections.status = $4
+			RETURNING `+connectionColumns, requesterID, addresseeID, models.StatusPending, models.StatusDeclined))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO connection_request_log (requester_id, addressee_id)
+			VALUES ($1, $2)`, requesterID, addresseeID)
+		if err != nil {
+			return err
+		}
+
+		return recordConnectionEvent(ctx, tx, requesterID, addresseeID, requesterID, models.ConnectionEventCreated)
+	})
 
-	_, err := db.pool.Exec(ctx, query, requesterID, addresseeID, models.StatusPending)
 	if err != nil {
-		return fmt.Errorf("failed to create connection: %w", err)
+		return nil, fmt.Errorf("failed to create connection: %w", err)
 	}
 
-	return nil
+	return connection, nil
+}
+
+// CountConnectionRequestsSince counts the connection requests a user has sent since the given time,
+// and returns when the oldest of them was sent (nil if there are none)
+func (db *DB) CountConnectionRequestsSince(ctx context.Context, requesterID uuid.UUID, since time.Time) (int, *time.Time, error) {
+	query := `SELECT COUNT(*), MIN(created_at) FROM connection_request_log WHERE requester_id = $1 AND created_at > $2`
+
+	var count int
+	var oldest *time.Time
+	if err := db.pool.QueryRow(ctx, query, requesterID, since).Scan(&count, &oldest); err != nil {
+		return 0, nil, fmt.Errorf("failed to count connection requests: %w", err)
+	}
+
+	return count, oldest, nil
 }
 
 // GetConnection retrieves a connection between two users
 func (db *DB) GetConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.UserConnection, error) {
-	connection := &models.UserConnection{}
 	query := `
-		SELECT id, requester_id, addressee_id, status, created_at, updated_at
+		SELECT ` + connectionColumns + `
 		FROM user_connections 
 		WHERE (requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1)`
 
-	err := db.pool.QueryRow(ctx, query, requesterID, addresseeID).Scan(
-		&connection.ID, &connection.RequesterID, &connection.AddresseeID,
-		&connection.Status, &connection.CreatedAt, &connection.UpdatedAt,
-	)
+	connection, err := scanConnection(db.pool.QueryRow(ctx, query, requesterID, addresseeID))
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -232,67 +527,384 @@ func (db *DB) GetConnection(ctx context.Context, requesterID, addresseeID uuid.U
 	return connection, nil
 }
 
-// AcceptConnection accepts a pending connection request
-func (db *DB) AcceptConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) error {
+// GetConnectionByID retrieves a connection by its id
+func (db *DB) GetConnectionByID(ctx context.Context, id uuid.UUID) (*models.UserConnection, error) {
+	query := `SELECT ` + connectionColumns + ` FROM user_connections WHERE id = $1`
+
+	connection, err := scanConnection(db.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("connection not found")
+		}
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	return connection, nil
+}
+
+// AreConnected reports whether two users have an accepted connection
+func (db *DB) AreConnected(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
 	query := `
-		UPDATE user_connections 
-		SET status = $1, updated_at = NOW()
-		WHERE requester_id = $2 AND addressee_id = $3 AND status = $4`
+		SELECT EXISTS (
+			SELECT 1 FROM user_connections
+			WHERE ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+			AND status = $3
+		)`
+
+	var connected bool
+	if err := db.pool.QueryRow(ctx, query, userID, otherID, models.StatusAccepted).Scan(&connected); err != nil {
+		return false, fmt.Errorf("failed to check connection: %w", err)
+	}
 
-	result, err := db.pool.Exec(ctx, query, models.StatusAccepted, requesterID, addresseeID, models.StatusPending)
+	return connected, nil
+}
+
+// ConnectedUserIDs returns which of the given users have an accepted connection with userID
+func (db *DB) ConnectedUserIDs(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	query := `
+		SELECT CASE WHEN requester_id = $1 THEN addressee_id ELSE requester_id END
+		FROM user_connections
+		WHERE ((requester_id = $1 AND addressee_id = ANY($2)) OR (addressee_id = $1 AND requester_id = ANY($2)))
+		AND status = $3`
+
+	rows, err := db.pool.Query(ctx, query, userID, otherIDs, models.StatusAccepted)
 	if err != nil {
-		return fmt.Errorf("failed to accept connection: %w", err)
+		return nil, fmt.Errorf("failed to check connections: %w", err)
 	}
+	defer rows.Close()
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("pending connection request not found")
+	connected := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan connection: %w", err)
+		}
+		connected[id] = true
 	}
 
-	return nil
+	return connected, nil
 }
 
-// DeclineConnection declines/cancels a connection request
-func (db *DB) DeclineConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) error {
+// CountMutualConnections returns how many accepted connections the two users share
+func (db *DB) CountMutualConnections(ctx context.Context, userID, otherID uuid.UUID) (int, error) {
 	query := `
-		DELETE FROM user_connections 
-		WHERE requester_id = $1 AND addressee_id = $2 AND status = $3`
+		WITH friends AS (
+			SELECT requester_id AS owner_id, addressee_id AS friend_id FROM user_connections WHERE status = $3
+			UNION ALL
+			SELECT addressee_id, requester_id FROM user_connections WHERE status = $3
+		)
+		SELECT COUNT(*)
+		FROM friends a
+		JOIN friends b ON b.friend_id = a.friend_id AND b.owner_id = $2
+		WHERE a.owner_id = $1`
+
+	var count int
+	if err := db.pool.QueryRow(ctx, query, userID, otherID, models.StatusAccepted).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count mutual connections: %w", err)
+	}
+
+	return count, nil
+}
+
+// ConnectionLimitError is returned by AcceptConnection when accepting would
+// take UserID past the maximum number of accepted connections
+type ConnectionLimitError struct {
+	UserID uuid.UUID
+}
+
+func (e *ConnectionLimitError) Error() string {
+	return fmt.Sprintf("user %s has reached the connection limit", e.UserID)
+}
+
+// AcceptConnection accepts a pending connection request and returns the updated row.
+// When maxConnections is positive and either user already has that many accepted
+// connections, it returns a *ConnectionLimitError and leaves the request pending.
+func (db *DB) AcceptConnection(ctx context.Context, requesterID, addresseeID uuid.UUID, maxConnections int) (*models.UserConnection, error) {
+	var connection *models.UserConnection
+
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		if maxConnections > 0 {
+			// Lock both users in a fixed order so concurrent accepts can't both slip under the cap
+			_, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`, requesterID, addresseeID)
+			if err != nil {
+				return fmt.Errorf("failed to lock users: %w", err)
+			}
+
+			for _, userID := range []uuid.UUID{addresseeID, requesterID} {
+				count, err := countConnections(ctx, tx, userID)
+				if err != nil {
+					return err
+				}
+				if count >= maxConnections {
+					return &ConnectionLimitError{UserID: userID}
+				}
+			}
+		}
+
+		query := `
+			UPDATE user_connections 
+			SET status = $1, updated_at = NOW()
+			WHERE requester_id = $2 AND addressee_id = $3 AND status = $4
+			RETURNING ` + connectionColumns
+
+		var err error
+		connection, err = scanConnection(tx.QueryRow(ctx, query, models.StatusAccepted, requesterID, addresseeID, models.StatusPending))
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("pending connection request not found")
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
 
-	result, err := db.pool.Exec(ctx, query, requesterID, addresseeID, models.StatusPending)
+		return recordConnectionEvent(ctx, tx, requesterID, addresseeID, addresseeID, models.ConnectionEventAccepted)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to decline connection: %w", err)
+		return nil, err
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("pending connection request not found")
+	return connection, nil
+}
+
+// countConnections returns how many accepted connections userID has
+func countConnections(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM user_connections
+		WHERE (requester_id = $1 OR addressee_id = $1) AND status = $2`
+
+	var count int
+	if err := tx.QueryRow(ctx, query, userID, models.StatusAccepted).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count connections: %w", err)
 	}
 
-	return nil
+	return count, nil
+}
+
+// DeclineConnection declines a connection request and returns the updated row.
+// The row is kept with the declined status so re-sending can be throttled.
+func (db *DB) DeclineConnection(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.UserConnection, error) {
+	var connection *models.UserConnection
+
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		query := `
+			UPDATE user_connections 
+			SET status = $1, updated_at = NOW()
+			WHERE requester_id = $2 AND addressee_id = $3 AND status = $4
+			RETURNING ` + connectionColumns
+
+		var err error
+		connection, err = scanConnection(tx.QueryRow(ctx, query, models.StatusDeclined, requesterID, addresseeID, models.StatusPending))
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("pending connection request not found")
+			}
+			return fmt.Errorf("failed to decline connection: %w", err)
+		}
+
+		return recordConnectionEvent(ctx, tx, requesterID, addresseeID, addresseeID, models.ConnectionEventDeclined)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return connection, nil
 }
 
 // RemoveConnection removes an existing friendship
 func (db *DB) RemoveConnection(ctx context.Context, userID, friendID uuid.UUID) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		query := `
+			DELETE FROM user_connections 
+			WHERE ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+			AND status = $3
+			RETURNING requester_id, addressee_id`
+
+		var requesterID, addresseeID uuid.UUID
+		err := tx.QueryRow(ctx, query, userID, friendID, models.StatusAccepted).Scan(&requesterID, &addresseeID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("friendship not found")
+			}
+			return fmt.Errorf("failed to remove connection: %w", err)
+		}
+
+		if err := deleteConnectionTags(ctx, tx, requesterID, addresseeID); err != nil {
+			return err
+		}
+		return recordConnectionEvent(ctx, tx, requesterID, addresseeID, userID, models.ConnectionEventRemoved)
+	})
+}
+
+var (
+	// ErrConnectionNotFound is returned when no accepted connection has the given ID
+	ErrConnectionNotFound = errors.New("connection not found")
+	// ErrNotConnectionParticipant is returned when a user acts on a connection they are not part of
+	ErrNotConnectionParticipant = errors.New("not a participant in the connection")
+)
+
+// RemoveConnectionByID removes an accepted connection by its ID if requestingUserID is one
+// of its two users. Pending and declined rows are left alone, as with RemoveConnection.
+func (db *DB) RemoveConnectionByID(ctx context.Context, connectionID, requestingUserID uuid.UUID) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		query := `
+			SELECT requester_id, addressee_id FROM user_connections
+			WHERE id = $1 AND status = $2
+			FOR UPDATE`
+
+		var requesterID, addresseeID uuid.UUID
+		err := tx.QueryRow(ctx, query, connectionID, models.StatusAccepted).Scan(&requesterID, &addresseeID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrConnectionNotFound
+			}
+			return fmt.Errorf("failed to remove connection: %w", err)
+		}
+		if requestingUserID != requesterID && requestingUserID != addresseeID {
+			return ErrNotConnectionParticipant
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM user_connections WHERE id = $1`, connectionID); err != nil {
+			return fmt.Errorf("failed to remove connection: %w", err)
+		}
+		if err := deleteConnectionTags(ctx, tx, requesterID, addresseeID); err != nil {
+			return err
+		}
+
+		return recordConnectionEvent(ctx, tx, requesterID, addresseeID, requestingUserID, models.ConnectionEventRemoved)
+	})
+}
+
+// ExpirePendingConnections deletes pending requests sent at or before cutoff and
+// returns them, so their senders can be told. Expiry is not something either user
+// did, so no connection event is recorded.
+func (db *DB) ExpirePendingConnections(ctx context.Context, cutoff time.Time) ([]models.UserConnection, error) {
 	query := `
-		DELETE FROM user_connections 
-		WHERE ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
-		AND status = $3`
+		DELETE FROM user_connections
+		WHERE status = $1 AND created_at <= $2
+		RETURNING ` + connectionColumns
 
-	result, err := db.pool.Exec(ctx, query, userID, friendID, models.StatusAccepted)
+	rows, err := db.pool.Query(ctx, query, models.StatusPending, cutoff)
 	if err != nil {
-		return fmt.Errorf("failed to remove connection: %w", err)
+		return nil, fmt.Errorf("failed to expire pending requests: %w", err)
 	}
+	defer rows.Close()
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("friendship not found")
+	expired := make([]models.UserConnection, 0)
+	for rows.Next() {
+		connection, err := scanConnection(rows)
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, *connection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to expire pending requests: %w", err)
 	}
 
-	return nil
+	return expired, nil
+}
+
+// GetConnectionCounts counts a user's accepted connections and pending requests in one query
+func (db *DB) GetConnectionCounts(ctx context.Context, userID uuid.UUID) (*models.ConnectionCounts, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE status = $2),
+		       COUNT(*) FILTER (WHERE status = $3 AND addressee_id = $1),
+		       COUNT(*) FILTER (WHERE status = $3 AND requester_id = $1)
+		FROM user_connections
+		WHERE requester_id = $1 OR addressee_id = $1`
+
+	counts := &models.ConnectionCounts{}
+	err := db.pool.QueryRow(ctx, query, userID, models.StatusAccepted, models.StatusPending).Scan(
+		&counts.Connections, &counts.PendingIncoming, &counts.PendingOutgoing,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count connections: %w", err)
+	}
+
+	return counts, nil
 }
 
-// GetUserConnections retrieves all accepted connections for a user
-func (db *DB) GetUserConnections(ctx context.Context, userID uuid.UUID) ([]models.ConnectionWithUser, error) {
+// GetConnectionOverview returns one page of each of a user's accepted connections,
+// incoming requests and outgoing requests, with each section's total, in one query.
+// Sections are ordered like GetUserConnections (by name) and GetPendingConnectionRequests
+// (newest first), and limit and offset apply to each section separately.
+func (db *DB) GetConnectionOverview(ctx context.Context, userID uuid.UUID, limit, offset int) (*models.ConnectionOverview, error) {
+	query := `
+		WITH related AS (
+			SELECT uc.id, uc.requester_id, uc.addressee_id, uc.status, uc.created_at, uc.updated_at,
+			       u.id AS user_id, u.username, u.display_name, u.created_at AS user_created_at,
+			       CASE WHEN uc.status = $2 AND (u.status_expires_at IS NULL OR u.status_expires_at > NOW())
+			            THEN u.status_message END AS status_message,
+			       CASE WHEN uc.status = $2 THEN 'accepted'
+			            WHEN uc.addressee_id = $1 THEN 'incoming'
+			            ELSE 'outgoing' END AS section
+			FROM user_connections uc
+			JOIN users u ON u.id = CASE WHEN uc.requester_id = $1 THEN uc.addressee_id ELSE uc.requester_id END
+			WHERE (uc.requester_id = $1 OR uc.addressee_id = $1) AND uc.status IN ($2, $3)
+		), ranked AS (
+			SELECT *,
+			       ROW_NUMBER() OVER (
+			           PARTITION BY section
+			           ORDER BY CASE WHEN section = 'accepted' THEN display_name END, created_at DESC, id
+			       ) AS position,
+			       COUNT(*) OVER (PARTITION BY section) AS total
+			FROM related
+		)
+		-- The first row of every section is always returned so its total is known
+		-- even when the page is past its end
+		SELECT section, position, total,
+		       id, requester_id, addressee_id, status, created_at, updated_at,
+		       user_id, username, display_name, user_created_at, status_message
+		FROM ranked
+		WHERE position = 1 OR (position > $4 AND position <= $4 + $5)
+		ORDER BY section, position`
+
+	rows, err := db.pool.Query(ctx, query, userID, models.StatusAccepted, models.StatusPending, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection overview: %w", err)
+	}
+	defer rows.Close()
+
+	overview := models.NewConnectionOverview(limit, offset)
+	sections := map[string]*models.ListResponse[models.ConnectionWithUser]{
+		"accepted": &overview.Accepted,
+		"incoming": &overview.Incoming,
+		"outgoing": &overview.Outgoing,
+	}
+	for rows.Next() {
+		var section string
+		var position, total int
+		var conn models.ConnectionWithUser
+		err := rows.Scan(
+			&section, &position, &total,
+			&conn.Connection.ID, &conn.Connection.RequesterID, &conn.Connection.AddresseeID,
+			&conn.Connection.Status, &conn.Connection.CreatedAt, &conn.Connection.UpdatedAt,
+			&conn.User.ID, &conn.User.Username, &conn.User.DisplayName, &conn.User.CreatedAt,
+			&conn.User.StatusMessage,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan connection overview: %w", err)
+		}
+
+		list := sections[section]
+		*list.Pagination.Total = total
+		if position > offset {
+			list.Data = append(list.Data, conn)
+		}
+	}
+
+	return overview, nil
+}
+
+// GetUserConnections retrieves all accepted connections for a user, with the user's
+// own tags on each. A non-empty tag keeps only the connections with that tag.
+func (db *DB) GetUserConnections(ctx context.Context, userID uuid.UUID, tag string) ([]models.ConnectionWithUser, error) {
 	query := `
 		SELECT uc.id, uc.requester_id, uc.addressee_id, uc.status, uc.created_at, uc.updated_at,
-		       u.id, u.username, u.display_name, u.created_at
+		       u.id, u.username, u.display_name, u.created_at,
+		       -- Connections see each other's status message until it expires
+		       CASE WHEN u.status_expires_at IS NULL OR u.status_expires_at > NOW() THEN u.status_message END,
+		       -- The user's own private tags on this connection
+		       ARRAY(SELECT ct.tag FROM connection_tags ct WHERE ct.user_id = $1 AND ct.connection_user_id = u.id ORDER BY ct.tag)
 		FROM user_connections uc
 		JOIN users u ON (
 			CASE 
@@ -301,21 +913,25 @@ func (db *DB) GetUserConnections(ctx context.Context, userID uuid.UUID) ([]model
 			END
 		)
 		WHERE (uc.requester_id = $1 OR uc.addressee_id = $1) AND uc.status = $2
+		AND ($3 = '' OR EXISTS (
+			SELECT 1 FROM connection_tags ct WHERE ct.user_id = $1 AND ct.connection_user_id = u.id AND ct.tag = $3
+		))
 		ORDER BY u.display_name`
 
-	rows, err := db.pool.Query(ctx, query, userID, models.StatusAccepted)
+	rows, err := db.pool.Query(ctx, query, userID, models.StatusAccepted, tag)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user connections: %w", err)
 	}
 	defer rows.Close()
 
-	var connections []models.ConnectionWithUser
+	connections := make([]models.ConnectionWithUser, 0)
 	for rows.Next() {
 		var conn models.ConnectionWithUser
 		err := rows.Scan(
 			&conn.Connection.ID, &conn.Connection.RequesterID, &conn.Connection.AddresseeID,
 			&conn.Connection.Status, &conn.Connection.CreatedAt, &conn.Connection.UpdatedAt,
 			&conn.User.ID, &conn.User.Username, &conn.User.DisplayName, &conn.User.CreatedAt,
+			&conn.User.StatusMessage, &conn.Tags,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan connection: %w", err)
@@ -326,23 +942,65 @@ func (db *DB) GetUserConnections(ctx context.Context, userID uuid.UUID) ([]model
 	return connections, nil
 }
 
-// GetPendingConnectionRequests retrieves all pending incoming connection requests for a user
-func (db *DB) GetPendingConnectionRequests(ctx context.Context, userID uuid.UUID) ([]models.ConnectionWithUser, error) {
+// GetConnectionsOf returns a page of ownerID's accepted connections as viewerID may
+// see them, ordered by username, with the total. Private profiles not connected to
+// the viewer and users in a block with the viewer are left out.
+func (db *DB) GetConnectionsOf(ctx context.Context, ownerID, viewerID uuid.UUID, limit, offset int) ([]models.UserLimited, int, error) {
+	from := `
+		FROM user_connections c
+		JOIN users ON users.id = CASE WHEN c.requester_id = $1 THEN c.addressee_id ELSE c.requester_id END
+		WHERE (c.requester_id = $1 OR c.addressee_id = $1) AND c.status = $2
+		  AND ` + visibleToViewer("$3") + `
+		  AND ` + notBlocked("$3")
+	query := `
+		SELECT users.id, users.username, users.display_name, COUNT(*) OVER () AS total` + from + `
+		ORDER BY users.username, users.id
+		LIMIT $4 OFFSET $5`
+
+	rows, err := db.pool.Query(ctx, query, ownerID, models.StatusAccepted, viewerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get connections: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.UserLimited, 0)
+	total := 0
+	for rows.Next() {
+		var user models.UserLimited
+		if err := rows.Scan(&user.ID, &user.Username, &user.DisplayName, &total); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan connection: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	// The window count arrives with the rows, so a page past the end counts separately
+	if len(users) == 0 && offset > 0 {
+		if err := db.pool.QueryRow(ctx, `SELECT COUNT(*)`+from, ownerID, models.StatusAccepted, viewerID).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count connections: %w", err)
+		}
+	}
+
+	return users, total, nil
+}
+
+// GetPendingConnectionRequests retrieves the pending incoming connection requests for
+// a user sent after since; the zero time returns all of them
+func (db *DB) GetPendingConnectionRequests(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.ConnectionWithUser, error) {
 	query := `
 		SELECT uc.id, uc.requester_id, uc.addressee_id, uc.status, uc.created_at, uc.updated_at,
 		       u.id, u.username, u.display_name, u.created_at
 		FROM user_connections uc
 		JOIN users u ON u.id = uc.requester_id
-		WHERE uc.addressee_id = $1 AND uc.status = $2
+		WHERE uc.addressee_id = $1 AND uc.status = $2 AND uc.created_at > $3
 		ORDER BY uc.created_at DESC`
 
-	rows, err := db.pool.Query(ctx, query, userID, models.StatusPending)
+	rows, err := db.pool.Query(ctx, query, userID, models.StatusPending, since)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending requests: %w", err)
 	}
 	defer rows.Close()
 
-	var requests []models.ConnectionWithUser
+	requests := make([]models.ConnectionWithUser, 0)
 	for rows.Next() {
 		var req models.ConnectionWithUser
 		err := rows.Scan(
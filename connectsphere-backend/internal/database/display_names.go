@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// displayNameIndex enforces case-insensitive display name uniqueness when UNIQUE_DISPLAY_NAMES is on
+const displayNameIndex = "idx_users_display_name_lower"
+
+// ErrDisplayNameTaken is returned when a display name is already used by another account
+var ErrDisplayNameTaken = errors.New("display name already in use")
+
+// SetUniqueDisplayNames creates or drops the unique display name index so the
+// database matches the UNIQUE_DISPLAY_NAMES setting. Enabling it fails if
+// existing users already share a display name.
+func (db *DB) SetUniqueDisplayNames(ctx context.Context, enabled bool) error {
+	query := `DROP INDEX IF EXISTS ` + displayNameIndex
+	if enabled {
+		query = `CREATE UNIQUE INDEX IF NOT EXISTS ` + displayNameIndex + ` ON users (LOWER(display_name))`
+	}
+
+	if _, err := db.pool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to update display name index: %w", err)
+	}
+
+	return nil
+}
+
+// IsDisplayNameTaken reports whether another user already has this display name (case-insensitive)
+func (db *DB) IsDisplayNameTaken(ctx context.Context, displayName string, excludeUserID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(display_name) = LOWER($1) AND id <> $2)`
+
+	var taken bool
+	if err := db.pool.QueryRow(ctx, query, displayName, excludeUserID).Scan(&taken); err != nil {
+		return false, fmt.Errorf("failed to check display name: %w", err)
+	}
+
+	return taken, nil
+}
+
+// isDisplayNameConflict reports whether err is a violation of the display name index
+func isDisplayNameConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == displayNameIndex
+}
+
+// DisplayNameCooldownError is returned by UpdateUser when the display name was
+// changed too recently to change it again
+type DisplayNameCooldownError struct {
+	RetryAt time.Time // When the next change is allowed
+}
+
+func (e *DisplayNameCooldownError) Error() string {
+	return "display name changed too recently; next change allowed at " + e.RetryAt.UTC().Format(time.RFC3339)
+}
+
+// recordDisplayNameChange adds a history entry if newName differs from the user's
+// current display name, enforcing cooldown (0 disables it) since the last change.
+// The user row stays locked until the transaction ends, so concurrent changes
+// can't both slip through the cooldown.
+func recordDisplayNameChange(ctx context.Context, tx pgx.Tx, userID uuid.UUID, newName string, cooldown time.Duration) error {
+	var current string
+	err := tx.QueryRow(ctx, `SELECT display_name FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&current)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to check display name: %w", err)
+	}
+	if current == newName {
+		return nil
+	}
+
+	if cooldown > 0 {
+		var lastChange *time.Time
+		err := tx.QueryRow(ctx, `SELECT MAX(changed_at) FROM display_name_history WHERE user_id = $1`, userID).Scan(&lastChange)
+		if err != nil {
+			return fmt.Errorf("failed to check display name history: %w", err)
+		}
+		if lastChange != nil {
+			if retryAt := lastChange.Add(cooldown); time.Now().Before(retryAt) {
+				return &DisplayNameCooldownError{RetryAt: retryAt}
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO display_name_history (user_id, old_display_name, new_display_name)
+		VALUES ($1, $2, $3)`, userID, current, newName)
+	if err != nil {
+		return fmt.Errorf("failed to record display name change: %w", err)
+	}
+	return nil
+}
+
+// ListDisplayNameHistory returns a user's display name changes, newest first
+func (db *DB) ListDisplayNameHistory(ctx context.Context, userID uuid.UUID) ([]models.DisplayNameChange, error) {
+	query := `
+		SELECT id, old_display_name, new_display_name, changed_at
+		FROM display_name_history
+		WHERE user_id = $1
+		ORDER BY changed_at DESC, id`
+
+	rows, err := db.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list display name history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]models.DisplayNameChange, 0)
+	for rows.Next() {
+		var change models.DisplayNameChange
+		if err := rows.Scan(&change.ID, &change.OldDisplayName, &change.NewDisplayName, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan display name change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrEmailTaken is returned when an email is already used by or pending for another account
+	ErrEmailTaken = errors.New("email already in use")
+	// ErrInvalidToken is returned when a verification token is unknown or expired
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// Email change operations
+
+// IsEmailTaken reports whether an email is used by another user or pending confirmation for one
+func (db *DB) IsEmailTaken(ctx context.Context, email string, excludeUserID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1) AND id <> $2)
+		    OR EXISTS (
+		       SELECT 1 FROM email_change_requests
+		       WHERE LOWER(new_email) = LOWER($1) AND user_id <> $2 AND expires_at > NOW()
+		    )`
+
+	var taken bool
+	if err := db.pool.QueryRow(ctx, query, email, excludeUserID).Scan(&taken); err != nil {
+		return false, fmt.Errorf("failed to check email: %w", err)
+	}
+
+	return taken, nil
+}
+
+// CreateEmailChangeRequest stores a pending email change, replacing any earlier one for the user
+func (db *DB) CreateEmailChangeRequest(ctx context.Context, userID uuid.UUID, newEmail, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO email_change_requests (user_id, new_email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET new_email = EXCLUDED.new_email,
+		    token_hash = EXCLUDED.token_hash,
+		    created_at = NOW(),
+		    expires_at = EXCLUDED.expires_at`
+
+	if _, err := db.pool.Exec(ctx, query, userID, newEmail, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create email change request: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange swaps the pending email into the user's account and returns the updated user
+func (db *DB) ConfirmEmailChange(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user *models.User
+
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		var userID uuid.UUID
+		var newEmail string
+		err := tx.QueryRow(ctx, `
+			DELETE FROM email_change_requests
+			WHERE token_hash = $1 AND expires_at > NOW()
+			RETURNING user_id, new_email`, tokenHash).Scan(&userID, &newEmail)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrInvalidToken
+			}
+			return fmt.Errorf("failed to get email change request: %w", err)
+		}
+
+		var taken bool
+		err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1) AND id <> $2)`,
+			newEmail, userID).Scan(&taken)
+		if err != nil {
+			return fmt.Errorf("failed to check email: %w", err)
+		}
+		if taken {
+			return ErrEmailTaken
+		}
+
+		user, err = scanUser(tx.QueryRow(ctx, `
+			UPDATE users SET email = $1, updated_at = NOW()
+			WHERE id = $2
+			RETURNING `+userColumns, newEmail, userID))
+		if err != nil {
+			return fmt.Errorf("failed to update email: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// healthCheckTimeout bounds a single health check ping
+const healthCheckTimeout = 2 * time.Second
+
+// HealthCheck pings the database, failing if it does not answer in time
+func (db *DB) HealthCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	if err := db.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("database ping failed: %w", err)
+	}
+
+	return nil
+}
+
+// MonitorHealth pings the database every interval until ctx is cancelled,
+// logging when the database becomes unreachable and when it recovers
+func (db *DB) MonitorHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := db.HealthCheck(ctx)
+		switch {
+		case err != nil && healthy:
+			stat := db.pool.Stat()
+			log.Printf("Database degraded: %v (total_conns=%d idle_conns=%d)", err, stat.TotalConns(), stat.IdleConns())
+		case err == nil && !healthy:
+			log.Println("Database recovered")
+		}
+		healthy = err == nil
+	}
+}
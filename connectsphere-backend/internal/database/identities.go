@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// External identity operations
+
+// ErrRegistrationClosed is returned when a new account would have to be created
+// while registration is closed
+var ErrRegistrationClosed = errors.New("registration is closed")
+
+// GetOrCreateExternalUser returns the user linked to an identity provider's issuer
+// and subject. On first sign-in the identity is linked to the account with the
+// same email if the provider verified that email, or newUser is created.
+// Creating newUser follows registrationMode like registering does: it fails with
+// ErrRegistrationClosed when closed, and consumes inviteCode when invite.
+// ErrEmailTaken is returned when an unverified email matches an existing account, and
+// ErrUsernameTaken or ErrDisplayNameTaken when newUser's names are already in use.
+func (db *DB) GetOrCreateExternalUser(ctx context.Context, issuer, subject string, emailVerified bool, newUser *models.User, registrationMode, inviteCode string) (*models.User, error) {
+	var user *models.User
+
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		user, err = scanUser(tx.QueryRow(ctx, `
+			SELECT `+userColumns+` FROM users
+			WHERE id = (SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2)`,
+			issuer, subject))
+		if err == nil {
+			return nil
+		}
+		if err != pgx.ErrNoRows {
+			return fmt.Errorf("failed to get user by identity: %w", err)
+		}
+
+		user, err = scanUser(tx.QueryRow(ctx,
+			`SELECT `+userColumns+` FROM users WHERE LOWER(email) = LOWER($1)`, newUser.Email))
+		switch {
+		case err == nil && !emailVerified:
+			return ErrEmailTaken
+		case err == pgx.ErrNoRows:
+			if registrationMode == "closed" {
+				return ErrRegistrationClosed
+			}
+			if err := insertUser(ctx, tx, newUser); err != nil {
+				return err
+			}
+			if registrationMode == "invite" {
+				if err := useInviteCode(ctx, tx, inviteCode, newUser.ID); err != nil {
+					return err
+				}
+			}
+			user = newUser
+		case err != nil:
+			return fmt.Errorf("failed to get user by email: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO user_identities (issuer, subject, user_id)
+			VALUES ($1, $2, $3)`, issuer, subject, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to link identity: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
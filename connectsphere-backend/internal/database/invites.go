@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidInviteCode is returned when an invite code is unknown, used or expired
+var ErrInvalidInviteCode = errors.New("invalid invite code")
+
+// Invite code operations
+
+// inviteCodeColumns lists the invite_codes columns in the order scanInviteCode expects them
+const inviteCodeColumns = `code, created_by, created_at, expires_at, used_by, used_at`
+
+// scanInviteCode scans a row selected with inviteCodeColumns into an InviteCode
+func scanInviteCode(row pgx.Row, extra ...any) (*models.InviteCode, error) {
+	invite := &models.InviteCode{}
+	err := row.Scan(append([]any{
+		&invite.Code, &invite.CreatedBy, &invite.CreatedAt, &invite.ExpiresAt, &invite.UsedBy, &invite.UsedAt,
+	}, extra...)...)
+	return invite, err
+}
+
+// CreateInviteCode stores a new single-use invite code; a nil expiresAt never expires
+func (db *DB) CreateInviteCode(ctx context.Context, code string, createdBy uuid.UUID, expiresAt *time.Time) (*models.InviteCode, error) {
+	query := `
+		INSERT INTO invite_codes (code, created_by, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING ` + inviteCodeColumns
+
+	invite, err := scanInviteCode(db.pool.QueryRow(ctx, query, code, createdBy, expiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite code: %w", err)
+	}
+
+	return invite, nil
+}
+
+// ListInviteCodes returns a page of invite codes, newest first, and the total count
+func (db *DB) ListInviteCodes(ctx context.Context, limit, offset int) ([]models.InviteCode, int, error) {
+	query := `
+		SELECT ` + inviteCodeColumns + `, COUNT(*) OVER () AS total
+		FROM invite_codes
+		ORDER BY created_at DESC, code
+		LIMIT $1 OFFSET $2`
+
+	rows, err := db.pool.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list invite codes: %w", err)
+	}
+	defer rows.Close()
+
+	invites := make([]models.InviteCode, 0)
+	total := 0
+	for rows.Next() {
+		invite, err := scanInviteCode(rows, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan invite code: %w", err)
+		}
+		invites = append(invites, *invite)
+	}
+
+	// The window count arrives with the rows, so a page past the end counts separately
+	if len(invites) == 0 && offset > 0 {
+		if err := db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM invite_codes`).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count invite codes: %w", err)
+		}
+	}
+
+	return invites, total, nil
+}
+
+// CreateUserWithInvite creates the user and consumes the invite code in one
+// transaction, returning ErrInvalidInviteCode if the code can't be used
+func (db *DB) CreateUserWithInvite(ctx context.Context, user *models.User, code string) error {
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := insertUser(ctx, tx, user); err != nil {
+			return err
+		}
+		return useInviteCode(ctx, tx, code, user.ID)
+	})
+}
+
+// useInviteCode marks code as used by userID, returning ErrInvalidInviteCode if
+// it is unknown, used or expired
+func useInviteCode(ctx context.Context, tx pgx.Tx, code string, userID uuid.UUID) error {
+	result, err := tx.Exec(ctx, `
+		UPDATE invite_codes
+		SET used_by = $1, used_at = NOW()
+		WHERE code = $2 AND used_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`,
+		userID, code)
+	if err != nil {
+		return fmt.Errorf("failed to use invite code: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrInvalidInviteCode
+	}
+
+	return nil
+}
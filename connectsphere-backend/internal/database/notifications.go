@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Notification operations
+
+// notificationColumns lists the notifications columns in the order they are scanned
+const notificationColumns = `id, user_id, type, payload, read_at, created_at`
+
+// CreateNotification stores a notification for userID and returns it
+func (db *DB) CreateNotification(ctx context.Context, userID uuid.UUID, notificationType string, payload json.RawMessage) (*models.Notification, error) {
+	query := `
+		INSERT INTO notifications (user_id, type, payload)
+		VALUES ($1, $2, $3)
+		RETURNING ` + notificationColumns
+
+	n := &models.Notification{}
+	err := db.pool.QueryRow(ctx, query, userID, notificationType, payload).Scan(
+		&n.ID, &n.UserID, &n.Type, &n.Payload, &n.ReadAt, &n.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return n, nil
+}
+
+// ListNotifications returns a page of the user's notifications, newest first,
+// along with the total number matching
+func (db *DB) ListNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	from := `
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)`
+	query := `
+		SELECT ` + notificationColumns + `, COUNT(*) OVER () AS total` + from + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := db.pool.Query(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	total := 0
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Payload, &n.ReadAt, &n.CreatedAt, &total); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	// The window count arrives with the rows, so a page past the end counts separately
+	if len(notifications) == 0 && offset > 0 {
+		if err := db.pool.QueryRow(ctx, `SELECT COUNT(*)`+from, userID, unreadOnly).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+		}
+	}
+
+	return notifications, total, nil
+}
+
+// MarkNotificationRead marks one of the user's notifications as read
+func (db *DB) MarkNotificationRead(ctx context.Context, userID, notificationID uuid.UUID) error {
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2`
+
+	result, err := db.pool.Exec(ctx, query, notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("notification not found")
+	}
+
+	return nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read
+// and returns how many were updated
+func (db *DB) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`
+
+	result, err := db.pool.Exec(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"connectsphere-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// RelationshipState loads the connection (if any) and block status between
+// userID and otherID in a single round-trip
+func (db *DB) RelationshipState(ctx context.Context, userID, otherID uuid.UUID) (*models.Relationship, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM blocked_users WHERE blocker_id = $1 AND blocked_id = $2),
+			EXISTS (SELECT 1 FROM blocked_users WHERE blocker_id = $2 AND blocked_id = $1),
+			uc.id, uc.requester_id, uc.addressee_id, uc.status, uc.created_at, uc.updated_at
+		FROM (SELECT 1) AS one
+		LEFT JOIN user_connections uc
+			ON (uc.requester_id = $1 AND uc.addressee_id = $2) OR (uc.requester_id = $2 AND uc.addressee_id = $1)
+		LIMIT 1`
+
+	rel := &models.Relationship{}
+	var (
+		id, requesterID, addresseeID *uuid.UUID
+		status                       *models.ConnectionStatus
+		createdAt, updatedAt         *time.Time
+	)
+
+	err := db.pool.QueryRow(ctx, query, userID, otherID).Scan(
+		&rel.BlockedByUser, &rel.BlockedByOther,
+		&id, &requesterID, &addresseeID, &status, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationship: %w", err)
+	}
+
+	if id != nil {
+		rel.Connection = &models.UserConnection{
+			ID:          *id,
+			RequesterID: *requesterID,
+			AddresseeID: *addresseeID,
+			Status:      *status,
+			CreatedAt:   *createdAt,
+			UpdatedAt:   *updatedAt,
+		}
+	}
+
+	return rel, nil
+}
+
+// RelationshipStatuses returns userID's relationship with each of otherIDs in a
+// single query, using the models.RelationshipStatus values. IDs with no connection, a declined one, or no such user are
+// RelationshipNone so the result doesn't reveal which accounts exist.
+func (db *DB) RelationshipStatuses(ctx context.Context, userID uuid.UUID, otherIDs []uuid.UUID) (map[uuid.UUID]models.RelationshipStatus, error) {
+	query := `
+		SELECT ids.id,
+			CASE
+				WHEN EXISTS (
+					SELECT 1 FROM blocked_users
+					WHERE (blocker_id = $1 AND blocked_id = ids.id) OR (blocker_id = ids.id AND blocked_id = $1)
+				) THEN 'blocked'
+				WHEN uc.status = $3 THEN 'connected'
+				WHEN uc.status = $4 AND uc.requester_id = $1 THEN 'pending_outgoing'
+				WHEN uc.status = $4 THEN 'pending_incoming'
+				ELSE 'none'
+			END
+		FROM unnest($2::uuid[]) AS ids(id)
+		LEFT JOIN LATERAL (
+			SELECT requester_id, status FROM user_connections
+			WHERE (requester_id = $1 AND addressee_id = ids.id) OR (requester_id = ids.id AND addressee_id = $1)
+			LIMIT 1
+		) uc ON true`
+
+	rows, err := db.pool.Query(ctx, query, userID, otherIDs, models.StatusAccepted, models.StatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationship statuses: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[uuid.UUID]models.RelationshipStatus, len(otherIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var status models.RelationshipStatus
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan relationship status: %w", err)
+		}
+		statuses[id] = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get relationship statuses: %w", err)
+	}
+
+	return statuses, nil
+}
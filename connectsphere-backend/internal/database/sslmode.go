@@ -0,0 +1,81 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// defaultSSLMode is applied when neither the database URL nor DB_SSLMODE sets one.
+// prefer uses TLS when the server offers it and falls back to plaintext otherwise.
+const defaultSSLMode = "prefer"
+
+// sslModeKeyword finds sslmode in a keyword/value connection string
+var sslModeKeyword = regexp.MustCompile(`(?:^|\s)sslmode=(\S*)`)
+
+// withSSLMode returns the connection string with its effective sslmode: override
+// if set, else the one already in the string, else defaultSSLMode
+func withSSLMode(databaseURL, override string) (string, string, error) {
+	if strings.HasPrefix(databaseURL, "postgres://") || strings.HasPrefix(databaseURL, "postgresql://") {
+		u, err := url.Parse(databaseURL)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse database URL: %w", err)
+		}
+
+		query := u.Query()
+		mode := query.Get("sslmode")
+		switch {
+		case override != "":
+			mode = override
+		case mode == "":
+			mode = defaultSSLMode
+			log.Printf("DATABASE_URL has no sslmode, using sslmode=%s (set DB_SSLMODE to change)", mode)
+		}
+		query.Set("sslmode", mode)
+		u.RawQuery = query.Encode()
+		return u.String(), mode, nil
+	}
+
+	// Keyword/value form (host=... user=...): a later sslmode takes precedence over an earlier one
+	mode := ""
+	if match := sslModeKeyword.FindStringSubmatch(databaseURL); match != nil {
+		mode = match[1]
+	}
+	switch {
+	case override != "":
+		return databaseURL + " sslmode=" + override, override, nil
+	case mode == "":
+		log.Printf("DATABASE_URL has no sslmode, using sslmode=%s (set DB_SSLMODE to change)", defaultSSLMode)
+		return databaseURL + " sslmode=" + defaultSSLMode, defaultSSLMode, nil
+	}
+	return databaseURL, mode, nil
+}
+
+// describeConnectError explains the most common causes of a failed first connection
+func describeConnectError(err error, sslMode string) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "28P01" || pgErr.Code == "28000") {
+		return "authentication failed, check the user and password in DATABASE_URL"
+	}
+
+	var (
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) || strings.Contains(err.Error(), "server refused TLS connection") {
+		return fmt.Sprintf("TLS negotiation failed with sslmode=%s; use a mode the server supports "+
+			"(e.g. disable for a local server without TLS, verify-full with a trusted certificate)", sslMode)
+	}
+
+	return "could not reach the database, check the host and port in DATABASE_URL"
+}
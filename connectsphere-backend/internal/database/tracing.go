@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"connectsphere-backend/internal/requestid"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryTracer logs queries that take at least threshold (if set), tagged with the
+// request ID from the query's context, and counts queries for contexts carrying a
+// QueryCounter. Only the parameterized SQL is logged, never the argument values.
+type queryTracer struct {
+	threshold time.Duration
+}
+
+// QueryCounter counts the queries run with a context from WithQueryCounter, e.g.
+// all the queries of one HTTP request. It is only updated when the pool was
+// created with PoolOptions.CountQueries.
+type QueryCounter struct {
+	n atomic.Int64
+}
+
+// Count returns the number of queries counted so far
+func (q *QueryCounter) Count() int64 {
+	return q.n.Load()
+}
+
+type queryCounterKey struct{}
+
+// WithQueryCounter returns a copy of ctx whose queries are counted by the returned counter
+func WithQueryCounter(ctx context.Context) (context.Context, *QueryCounter) {
+	counter := &QueryCounter{}
+	return context.WithValue(ctx, queryCounterKey{}, counter), counter
+}
+
+type queryTraceKey struct{}
+
+// queryTrace is what TraceQueryStart hands to TraceQueryEnd through the context
+type queryTrace struct {
+	sql   string
+	start time.Time
+}
+
+// TraceQueryStart counts the query and records when it started
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if counter, ok := ctx.Value(queryCounterKey{}).(*QueryCounter); ok {
+		counter.n.Add(1)
+	}
+	if t.threshold <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryTraceKey{}, queryTrace{sql: data.SQL, start: time.Now()})
+}
+
+// TraceQueryEnd logs the query if it ran for at least the threshold
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(queryTrace)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(trace.start)
+	if elapsed < t.threshold {
+		return
+	}
+
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		id = "-" // background work such as the health monitor
+	}
+	outcome := "ok"
+	if data.Err != nil {
+		outcome = "error=" + data.Err.Error()
+	}
+
+	// Collapse the indentation of multi-line queries onto one log line
+	sql := strings.Join(strings.Fields(trace.sql), " ")
+	log.Printf("[DB] slow query request_id=%s duration=%s %s sql=%s", id, elapsed, outcome, sql)
+}
@@ -0,0 +1,214 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// defaultSubscriberBuffer is used when HubOptions.SubscriberBuffer is not set
+const defaultSubscriberBuffer = 64
+
+// ErrTooManySubscriptions is returned by Subscribe when the user already has the
+// maximum number of live subscriptions
+var ErrTooManySubscriptions = errors.New("too many concurrent subscriptions")
+
+// Event is a message delivered to all of a user's live connections
+type Event struct {
+	ID   uint64
+	Type string
+	// Data is the JSON object sent to clients, including a "type" field
+	Data json.RawMessage
+}
+
+// Subscription receives the events published to one user
+type Subscription struct {
+	userID uuid.UUID
+	events chan Event
+}
+
+// Events returns the subscription's event channel. It is closed when the
+// subscription ends or falls too far behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// HubOptions configures a Hub
+type HubOptions struct {
+	// HistorySize is how many recent events are kept per user for resuming
+	HistorySize int
+	// MaxPerUser caps each user's live subscriptions; 0 means unlimited
+	MaxPerUser int
+	// SubscriberBuffer is how many undelivered events a subscriber may queue before
+	// it is disconnected; 0 uses the default of 64
+	SubscriberBuffer int
+}
+
+// Stats are counters describing the hub's current load
+type Stats struct {
+	Subscribers int `json:"subscribers"`
+	// SlowSubscribersDropped counts subscribers disconnected for a full buffer
+	SlowSubscribersDropped uint64 `json:"slow_subscribers_dropped"`
+}
+
+// Hub fans out per-user events to every transport the user is connected with.
+// Each user's most recent events are kept so clients can resume after a reconnect.
+//
+// Backpressure: each subscriber has a bounded buffer and Publish never blocks on
+// it. A subscriber whose buffer is full is disconnected instead of holding up
+// others; its client reconnects and replays what it missed from the history
+// with its last event ID.
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers map[uuid.UUID]map[*Subscription]struct{}
+	history     map[uuid.UUID][]Event
+	historySize int
+	maxPerUser  int
+	bufferSize  int
+
+	slowDropped atomic.Uint64
+}
+
+// NewHub creates a hub with the given options
+func NewHub(opts HubOptions) *Hub {
+	bufferSize := opts.SubscriberBuffer
+	if bufferSize <= 0 {
+		bufferSize = defaultSubscriberBuffer
+	}
+
+	return &Hub{
+		subscribers: make(map[uuid.UUID]map[*Subscription]struct{}),
+		history:     make(map[uuid.UUID][]Event),
+		historySize: opts.HistorySize,
+		maxPerUser:  opts.MaxPerUser,
+		bufferSize:  bufferSize,
+	}
+}
+
+// Stats returns the number of live subscribers and how many slow ones were dropped
+func (h *Hub) Stats() Stats {
+	h.mu.Lock()
+	subscribers := 0
+	for _, subs := range h.subscribers {
+		subscribers += len(subs)
+	}
+	h.mu.Unlock()
+
+	return Stats{
+		Subscribers:            subscribers,
+		SlowSubscribersDropped: h.slowDropped.Load(),
+	}
+}
+
+// Publish sends an event to userID. payload must marshal to a JSON object; the
+// event type is added to it as "type".
+func (h *Hub) Publish(userID uuid.UUID, eventType string, payload interface{}) error {
+	data, err := withType(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event{ID: h.lastID, Type: eventType, Data: data}
+
+	if h.historySize > 0 {
+		history := append(h.history[userID], event)
+		if len(history) > h.historySize {
+			history = history[len(history)-h.historySize:]
+		}
+		h.history[userID] = history
+	}
+
+	for sub := range h.subscribers[userID] {
+		select {
+		case sub.events <- event:
+		default:
+			// Too slow to keep up; the client resumes from its last event ID
+			h.remove(sub)
+			h.slowDropped.Add(1)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe registers a subscriber for userID. Events newer than lastEventID that
+// are still in the user's history are queued first; pass 0 to skip the replay.
+// New subscriptions beyond the per-user limit are refused with ErrTooManySubscriptions
+// rather than evicting an existing one, since an evicted client would reconnect
+// and evict another in turn.
+func (h *Hub) Subscribe(userID uuid.UUID, lastEventID uint64) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.maxPerUser > 0 && len(h.subscribers[userID]) >= h.maxPerUser {
+		return nil, ErrTooManySubscriptions
+	}
+
+	sub := &Subscription{userID: userID, events: make(chan Event, h.bufferSize)}
+
+	// IDs restart with the process, so an ID from the future means nothing can be replayed
+	if lastEventID > 0 && lastEventID <= h.lastID {
+		for _, event := range h.history[userID] {
+			if event.ID > lastEventID && len(sub.events) < cap(sub.events) {
+				sub.events <- event
+			}
+		}
+	}
+
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+
+	return sub, nil
+}
+
+// Unsubscribe removes the subscription and closes its channel
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub)
+}
+
+// remove drops a subscriber if it is still registered; callers hold h.mu
+func (h *Hub) remove(sub *Subscription) {
+	subs := h.subscribers[sub.userID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.userID)
+	}
+	close(sub.events)
+}
+
+// withType marshals payload and adds the event type to the resulting object
+func withType(eventType string, payload interface{}) (json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+		}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("%s event payload must be a JSON object: %w", eventType, err)
+		}
+	}
+
+	typeJSON, _ := json.Marshal(eventType)
+	fields["type"] = typeJSON
+
+	return json.Marshal(fields)
+}
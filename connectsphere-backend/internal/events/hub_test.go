@@ -0,0 +1,143 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSubscribeLimitPerUser(t *testing.T) {
+	const limit = 5
+	hub := NewHub(HubOptions{MaxPerUser: limit})
+	alice, bob := uuid.New(), uuid.New()
+
+	subs := make([]*Subscription, 0, limit)
+	for i := 0; i < limit; i++ {
+		sub, err := hub.Subscribe(alice, 0)
+		if err != nil {
+			t.Fatalf("subscription %d: %v", i+1, err)
+		}
+		subs = append(subs, sub)
+	}
+
+	// The one over the cap is refused and the existing ones keep working
+	if _, err := hub.Subscribe(alice, 0); !errors.Is(err, ErrTooManySubscriptions) {
+		t.Fatalf("subscription %d: err = %v, want ErrTooManySubscriptions", limit+1, err)
+	}
+	if err := hub.Publish(alice, "ping", nil); err != nil {
+		t.Fatal(err)
+	}
+	for i, sub := range subs {
+		if event, ok := <-sub.Events(); !ok || event.Type != "ping" {
+			t.Fatalf("subscription %d got %+v, %v after the refused one", i+1, event, ok)
+		}
+	}
+
+	// The cap is per user
+	if _, err := hub.Subscribe(bob, 0); err != nil {
+		t.Fatalf("another user's subscription: %v", err)
+	}
+
+	// Closing a stream frees its slot
+	hub.Unsubscribe(subs[0])
+	if _, err := hub.Subscribe(alice, 0); err != nil {
+		t.Fatalf("subscription after unsubscribing: %v", err)
+	}
+
+	if got := hub.Stats().Subscribers; got != limit+1 {
+		t.Fatalf("Stats().Subscribers = %d, want %d", got, limit+1)
+	}
+}
+
+func TestSubscribeUnlimited(t *testing.T) {
+	hub := NewHub(HubOptions{})
+	userID := uuid.New()
+
+	for i := 0; i < 100; i++ {
+		if _, err := hub.Subscribe(userID, 0); err != nil {
+			t.Fatalf("subscription %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	tests := []struct {
+		name        string
+		buffer      int
+		published   int
+		wantDropped bool
+	}{
+		{"within the buffer", 4, 4, false},
+		{"one over the buffer", 4, 5, true},
+		{"far over the buffer", 4, 50, true},
+		{"default buffer", 0, defaultSubscriberBuffer + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(HubOptions{HistorySize: 100, SubscriberBuffer: tt.buffer})
+			alice := uuid.New()
+			stuck, err := hub.Subscribe(alice, 0) // never read while publishing
+			if err != nil {
+				t.Fatal(err)
+			}
+			live, err := hub.Subscribe(alice, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Publish never blocks on the stuck subscriber, and the live one keeps receiving
+			for i := 0; i < tt.published; i++ {
+				if err := hub.Publish(alice, "ping", nil); err != nil {
+					t.Fatal(err)
+				}
+				if event, ok := <-live.Events(); !ok || event.Type != "ping" {
+					t.Fatalf("live subscriber got %+v, %v for event %d", event, ok, i+1)
+				}
+			}
+
+			// The stuck subscriber keeps what fitted in its buffer; once dropped its channel is closed
+			buffered, closed := 0, false
+		drain:
+			for {
+				select {
+				case _, ok := <-stuck.Events():
+					if !ok {
+						closed = true
+						break drain
+					}
+					buffered++
+				default:
+					break drain
+				}
+			}
+			if closed != tt.wantDropped {
+				t.Fatalf("stuck subscriber dropped = %v, want %v", closed, tt.wantDropped)
+			}
+			if want := min(tt.published, hub.bufferSize); buffered != want {
+				t.Fatalf("stuck subscriber had %d events queued, want %d", buffered, want)
+			}
+
+			wantStats := Stats{Subscribers: 2}
+			if tt.wantDropped {
+				wantStats = Stats{Subscribers: 1, SlowSubscribersDropped: 1}
+			}
+			if stats := hub.Stats(); stats != wantStats {
+				t.Fatalf("Stats() = %+v, want %+v", stats, wantStats)
+			}
+
+			// A dropped client resumes after the last event it received
+			if tt.wantDropped {
+				resumed, err := hub.Subscribe(alice, uint64(buffered))
+				if err != nil {
+					t.Fatal(err)
+				}
+				first := <-resumed.Events()
+				if first.ID != uint64(buffered)+1 {
+					t.Fatalf("resumed at event %d, want %d", first.ID, buffered+1)
+				}
+			}
+		})
+	}
+}
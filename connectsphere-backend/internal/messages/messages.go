@@ -0,0 +1,166 @@
+// Package messages holds the user-facing text for API error codes in each
+// supported language. Error codes themselves are never translated.
+package messages
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when a client accepts none of the supported languages
+const DefaultLanguage = "en"
+
+// catalogs maps a language to the message for each error code. Every code in
+// the English catalog should also be present in the others.
+var catalogs = map[string]map[string]string{
+	"en": {
+		"blocked":                  "You cannot interact with this user",
+		"block_not_found":          "Block not found",
+		"connection_exists":        "A connection or request already exists with this user",
+		"connection_limit_reached": "The connection limit has been reached",
+		"connection_not_found":     "Connection not found",
+		"display_name_cooldown":    "Display name was changed too recently; try again later",
+		"display_name_taken":       "Display name is already taken",
+		"email_required":           "An email address is required",
+		"email_taken":              "Email is already in use",
+		"forbidden":                "You are not allowed to do this",
+		"friendship_not_found":     "Friendship not found",
+		"internal_error":           "Something went wrong, please try again later",
+		"invalid_credentials":      "Invalid email, username or password",
+		"invalid_display_name":     "Display name is not valid",
+		"invalid_email":            "Email address is not valid",
+		"invalid_id":               "The identifier in the URL is not valid",
+		"invalid_invite_code":      "A valid, unused invite code is required to register",
+		"invalid_request":          "The request is not valid",
+		"invalid_status_message":   "Status message is not valid",
+		"invalid_tag":              "Tag is not valid",
+		"invalid_token":            "The link is invalid or has expired",
+		"invalid_username":         "Username is not valid",
+		"maintenance":              "The service is in maintenance mode; try again later",
+		"not_connected":            "You are not connected with this user",
+		"not_ready":                "The service is not ready",
+		"notification_not_found":   "Notification not found",
+		"profile_modified":         "The profile was changed elsewhere; reload it and try again",
+		"rate_limited":             "Too many requests, please try again later",
+		"registration_closed":      "Registration is closed",
+		"request_declined":         "This user recently declined your request",
+		"request_not_found":        "Connection request not found",
+		"requests_disabled":        "This user is not accepting connection requests",
+		"requests_restricted":      "This user only accepts requests from connections of their connections",
+		"session_not_found":        "Session not found",
+		"tag_limit_reached":        "This connection already has the maximum number of tags",
+		"tag_not_found":            "Tag not found",
+		"timeout":                  "The request took too long to process",
+		"too_many_streams":         "Too many event streams are open for this account",
+		"token_expired":            "Token has expired",
+		"token_invalid":            "Invalid token",
+		"unauthorized":             "Authentication is required",
+		"user_exists":              "A user with this email or username already exists",
+		"user_not_found":           "User not found",
+		"username_reserved":        "This username is reserved",
+		"username_taken":           "Username is already taken",
+		"validation_failed":        "One or more fields are invalid",
+	},
+	"es": {
+		"blocked":                  "No puedes interactuar con este usuario",
+		"block_not_found":          "Bloqueo no encontrado",
+		"connection_exists":        "Ya existe una conexión o solicitud con este usuario",
+		"connection_limit_reached": "Se ha alcanzado el límite de conexiones",
+		"connection_not_found":     "Conexión no encontrada",
+		"display_name_cooldown":    "El nombre para mostrar se cambió hace muy poco; inténtalo más tarde",
+		"display_name_taken":       "El nombre para mostrar ya está en uso",
+		"email_required":           "Se requiere una dirección de correo electrónico",
+		"email_taken":              "El correo electrónico ya está en uso",
+		"forbidden":                "No tienes permiso para hacer esto",
+		"friendship_not_found":     "Amistad no encontrada",
+		"internal_error":           "Algo salió mal, inténtalo de nuevo más tarde",
+		"invalid_credentials":      "Correo electrónico, nombre de usuario o contraseña no válidos",
+		"invalid_display_name":     "El nombre para mostrar no es válido",
+		"invalid_email":            "La dirección de correo electrónico no es válida",
+		"invalid_id":               "El identificador de la URL no es válido",
+		"invalid_invite_code":      "Se requiere un código de invitación válido y sin usar para registrarse",
+		"invalid_request":          "La solicitud no es válida",
+		"invalid_status_message":   "El mensaje de estado no es válido",
+		"invalid_tag":              "La etiqueta no es válida",
+		"invalid_token":            "El enlace no es válido o ha caducado",
+		"invalid_username":         "El nombre de usuario no es válido",
+		"maintenance":              "El servicio está en mantenimiento; inténtalo más tarde",
+		"not_connected":            "No estás conectado con este usuario",
+		"not_ready":                "El servicio no está listo",
+		"notification_not_found":   "Notificación no encontrada",
+		"profile_modified":         "El perfil se modificó en otro lugar; vuelve a cargarlo e inténtalo de nuevo",
+		"rate_limited":             "Demasiadas solicitudes, inténtalo de nuevo más tarde",
+		"registration_closed":      "El registro está cerrado",
+		"request_declined":         "Este usuario rechazó tu solicitud recientemente",
+		"request_not_found":        "Solicitud de conexión no encontrada",
+		"requests_disabled":        "Este usuario no acepta solicitudes de conexión",
+		"requests_restricted":      "Este usuario solo acepta solicitudes de contactos de sus contactos",
+		"session_not_found":        "Sesión no encontrada",
+		"tag_limit_reached":        "Esta conexión ya tiene el número máximo de etiquetas",
+		"tag_not_found":            "Etiqueta no encontrada",
+		"timeout":                  "La solicitud tardó demasiado en procesarse",
+		"too_many_streams":         "Hay demasiados flujos de eventos abiertos para esta cuenta",
+		"token_expired":            "El token ha caducado",
+		"token_invalid":            "Token no válido",
+		"unauthorized":             "Se requiere autenticación",
+		"user_exists":              "Ya existe un usuario con este correo electrónico o nombre de usuario",
+		"user_not_found":           "Usuario no encontrado",
+		"username_reserved":        "Este nombre de usuario está reservado",
+		"username_taken":           "El nombre de usuario ya está en uso",
+		"validation_failed":        "Uno o más campos no son válidos",
+	},
+}
+
+// Localize returns the message for code in lang, falling back to English when the
+// language or the code is missing from its catalog, and to "" for unknown codes.
+// Regional tags such as "es-MX" use the base language's catalog.
+func Localize(code, lang string) string {
+	if message, ok := catalogs[baseLanguage(lang)][code]; ok {
+		return message
+	}
+	return catalogs[DefaultLanguage][code]
+}
+
+// Negotiate picks the supported language the client prefers most from an
+// Accept-Language header, or DefaultLanguage if it accepts none of them
+func Negotiate(acceptLanguage string) string {
+	type preference struct {
+		lang    string
+		quality float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if tag == "" || quality <= 0 {
+			continue
+		}
+		preferences = append(preferences, preference{baseLanguage(tag), quality})
+	}
+
+	// Keep header order between equal weights
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+	for _, p := range preferences {
+		if _, ok := catalogs[p.lang]; ok {
+			return p.lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// baseLanguage reduces a language tag such as "es-MX" to its lowercase primary subtag
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return strings.ToLower(strings.TrimSpace(base))
+}
@@ -0,0 +1,74 @@
+package messages
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{"no header", "", "en"},
+		{"English", "en", "en"},
+		{"Spanish", "es", "es"},
+		{"regional tag uses the base language", "es-MX", "es"},
+		{"case-insensitive", "ES-mx", "es"},
+		{"unsupported language falls back", "fr", "en"},
+		{"first supported in order", "fr, es, en", "es"},
+		{"highest weight wins", "en;q=0.5, es;q=0.9", "es"},
+		{"default weight is 1", "es;q=0.8, en", "en"},
+		{"equal weights keep header order", "es;q=0.5, en;q=0.5", "es"},
+		{"zero weight means not acceptable", "es;q=0, fr", "en"},
+		{"malformed weight is skipped", "es;q=high, en;q=0.1", "en"},
+		{"wildcard", "*", "en"},
+		{"whitespace", "  fr ,  es-ES ; q=0.7 ", "es"},
+		{"empty entries", ",,es,", "es"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.acceptLanguage); got != tt.want {
+				t.Fatalf("Negotiate(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		lang string
+		want string
+	}{
+		{"English", "user_not_found", "en", "User not found"},
+		{"Spanish", "user_not_found", "es", "Usuario no encontrado"},
+		{"regional tag", "user_not_found", "es-AR", "Usuario no encontrado"},
+		{"unsupported language falls back to English", "user_not_found", "fr", "User not found"},
+		{"empty language", "user_not_found", "", "User not found"},
+		{"unknown code", "no_such_code", "es", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Localize(tt.code, tt.lang); got != tt.want {
+				t.Fatalf("Localize(%q, %q) = %q, want %q", tt.code, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for code := range catalogs[DefaultLanguage] {
+			if catalog[code] == "" {
+				t.Errorf("%s catalog has no message for %s", lang, code)
+			}
+		}
+		for code := range catalog {
+			if _, ok := catalogs[DefaultLanguage][code]; !ok {
+				t.Errorf("%s catalog has %s, which English does not", lang, code)
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,13 +10,20 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID             uuid.UUID `json:"id" db:"id"`
-	Username       string    `json:"username" db:"username"`
-	DisplayName    string    `json:"display_name" db:"display_name"`
-	Email          string    `json:"email" db:"email"`
-	HashedPassword string    `json:"-" db:"hashed_password"` // Never expose password in JSON
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	ID                      uuid.UUID  `json:"id" db:"id"`
+	Username                string     `json:"username" db:"username"`
+	DisplayName             string     `json:"display_name" db:"display_name"`
+	Email                   string     `json:"email" db:"email"`
+	HashedPassword          string     `json:"-" db:"hashed_password"` // Never expose password in JSON
+	ProfileVisibility       string     `json:"profile_visibility" db:"profile_visibility"`
+	DiscoverableByEmail     bool       `json:"discoverable_by_email" db:"discoverable_by_email"`
+	ConnectionRequestPolicy string     `json:"connection_request_policy" db:"connection_request_policy"`
+	ShowConnections         bool       `json:"show_connections" db:"show_connections"`
+	IsAdmin                 bool       `json:"is_admin" db:"is_admin"`
+	StatusMessage           *string    `json:"status_message" db:"status_message"`
+	StatusExpiresAt         *time.Time `json:"status_expires_at" db:"status_expires_at"`
+	CreatedAt               time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // UserPublic represents user data that can be publicly shared
@@ -23,15 +32,65 @@ type UserPublic struct {
 	Username    string    `json:"username"`
 	DisplayName string    `json:"display_name"`
 	CreatedAt   time.Time `json:"created_at"`
+	// StatusMessage is only filled in for the user's connections
+	StatusMessage *string `json:"status_message,omitempty"`
 }
 
-// UserAuth represents user data for authentication responses (includes email)
-type UserAuth struct {
+// UserLimited is the reduced view of a connections_only profile shown to non-connections
+type UserLimited struct {
 	ID          uuid.UUID `json:"id"`
 	Username    string    `json:"username"`
 	DisplayName string    `json:"display_name"`
-	Email       string    `json:"email"`
-	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UserAuth represents user data for authentication responses (includes email)
+type UserAuth struct {
+	ID                      uuid.UUID  `json:"id"`
+	Username                string     `json:"username"`
+	DisplayName             string     `json:"display_name"`
+	Email                   string     `json:"email"`
+	ProfileVisibility       string     `json:"profile_visibility"`
+	DiscoverableByEmail     bool       `json:"discoverable_by_email"`
+	ConnectionRequestPolicy string     `json:"connection_request_policy"`
+	ShowConnections         bool       `json:"show_connections"`
+	IsAdmin                 bool       `json:"is_admin"`
+	StatusMessage           *string    `json:"status_message"`
+	StatusExpiresAt         *time.Time `json:"status_expires_at"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"` // Send back as expected_updated_at to detect concurrent edits
+}
+
+// Profile visibility modes. Fields hidden from viewers who are not connected:
+//   - public: nothing hidden, the full UserPublic is returned
+//   - connections_only: created_at is hidden, only UserLimited is returned
+//   - private: the profile is hidden entirely (404) and excluded from search
+const (
+	VisibilityPublic          = "public"
+	VisibilityConnectionsOnly = "connections_only"
+	VisibilityPrivate         = "private"
+)
+
+// Connection request policies: who may send a user a new connection request
+const (
+	RequestPolicyEveryone                 = "everyone"
+	RequestPolicyConnectionsOfConnections = "connections_of_connections"
+	RequestPolicyNobody                   = "nobody"
+)
+
+// CurrentStatus returns the user's status message, or nil if none is set or it has expired
+func (u *User) CurrentStatus() *string {
+	if u.StatusExpiresAt != nil && !u.StatusExpiresAt.After(time.Now()) {
+		return nil
+	}
+	return u.StatusMessage
+}
+
+// ToConnectionView converts a User to the UserPublic shown to their connections,
+// which includes the current status message
+func (u *User) ToConnectionView() UserPublic {
+	public := u.ToPublic()
+	public.StatusMessage = u.CurrentStatus()
+	return public
 }
 
 // ToPublic converts a User to UserPublic (removes sensitive data)
@@ -44,50 +103,260 @@ func (u *User) ToPublic() UserPublic {
 	}
 }
 
-// ToAuth converts a User to UserAuth (includes email for authentication)
-func (u *User) ToAuth() UserAuth {
-	return UserAuth{
+// ToLimited converts a User to UserLimited (for connections_only profiles)
+func (u *User) ToLimited() UserLimited {
+	return UserLimited{
 		ID:          u.ID,
 		Username:    u.Username,
 		DisplayName: u.DisplayName,
-		Email:       u.Email,
-		CreatedAt:   u.CreatedAt,
 	}
 }
 
+// ToAuth converts a User to UserAuth (includes email for authentication)
+func (u *User) ToAuth() UserAuth {
+	auth := UserAuth{
+		ID:                      u.ID,
+		Username:                u.Username,
+		DisplayName:             u.DisplayName,
+		Email:                   u.Email,
+		ProfileVisibility:       u.ProfileVisibility,
+		DiscoverableByEmail:     u.DiscoverableByEmail,
+		ConnectionRequestPolicy: u.ConnectionRequestPolicy,
+		ShowConnections:         u.ShowConnections,
+		IsAdmin:                 u.IsAdmin,
+		StatusMessage:           u.CurrentStatus(),
+		CreatedAt:               u.CreatedAt,
+		UpdatedAt:               u.UpdatedAt,
+	}
+	if auth.StatusMessage != nil {
+		auth.StatusExpiresAt = u.StatusExpiresAt
+	}
+	return auth
+}
+
 // UserConnection represents a friendship/connection between users
 type UserConnection struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	RequesterID uuid.UUID `json:"requester_id" db:"requester_id"`
-	AddresseeID uuid.UUID `json:"addressee_id" db:"addressee_id"`
-	Status      string    `json:"status" db:"status"` // 'pending' or 'accepted'
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          uuid.UUID        `json:"id" db:"id"`
+	RequesterID uuid.UUID        `json:"requester_id" db:"requester_id"`
+	AddresseeID uuid.UUID        `json:"addressee_id" db:"addressee_id"`
+	Status      ConnectionStatus `json:"status" db:"status"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
 }
 
+// ConnectedAt returns when an accepted connection was accepted, or nil for other
+// statuses. Accepted rows are never updated again, so updated_at is the acceptance time.
+func (c UserConnection) ConnectedAt() *time.Time {
+	if c.Status != StatusAccepted {
+		return nil
+	}
+	connectedAt := c.UpdatedAt
+	return &connectedAt
+}
+
+// MarshalJSON adds connected_at to accepted connections so clients can sort
+// by when they connected without interpreting updated_at themselves
+func (c UserConnection) MarshalJSON() ([]byte, error) {
+	type connection UserConnection // drops this method to avoid recursion
+	return json.Marshal(struct {
+		connection
+		ConnectedAt *time.Time `json:"connected_at,omitempty"`
+	}{connection(c), c.ConnectedAt()})
+}
+
+// ConnectionStatus is the state of a UserConnection. The database enforces
+// the same set of values with a CHECK constraint on user_connections.status.
+type ConnectionStatus string
+
 // Connection statuses
 const (
-	StatusPending  = "pending"
-	StatusAccepted = "accepted"
+	StatusPending  ConnectionStatus = "pending"
+	StatusAccepted ConnectionStatus = "accepted"
+	StatusDeclined ConnectionStatus = "declined"
+)
+
+// Valid reports whether the status is one of the known connection statuses
+func (s ConnectionStatus) Valid() bool {
+	switch s {
+	case StatusPending, StatusAccepted, StatusDeclined:
+		return true
+	}
+	return false
+}
+
+// Scan implements sql.Scanner, rejecting unknown statuses read from the database
+func (s *ConnectionStatus) Scan(src interface{}) error {
+	var value ConnectionStatus
+	switch v := src.(type) {
+	case string:
+		value = ConnectionStatus(v)
+	case []byte:
+		value = ConnectionStatus(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ConnectionStatus", src)
+	}
+
+	if !value.Valid() {
+		return fmt.Errorf("invalid connection status %q", value)
+	}
+
+	*s = value
+	return nil
+}
+
+// Relationship is the combined connection and block state between a user and another user
+type Relationship struct {
+	Connection     *UserConnection // nil when no connection row exists
+	BlockedByUser  bool            // the user has blocked the other user
+	BlockedByOther bool            // the other user has blocked the user
+}
+
+// Blocked reports whether either user has blocked the other
+func (r *Relationship) Blocked() bool {
+	return r.BlockedByUser || r.BlockedByOther
+}
+
+// RelationshipStatus summarises a user's relationship with another user for display
+type RelationshipStatus string
+
+const (
+	RelationshipNone            RelationshipStatus = "none"
+	RelationshipPendingOutgoing RelationshipStatus = "pending_outgoing" // the user sent a request
+	RelationshipPendingIncoming RelationshipStatus = "pending_incoming" // the other user sent a request
+	RelationshipConnected       RelationshipStatus = "connected"
+	RelationshipBlocked         RelationshipStatus = "blocked" // either user blocked the other
+	RelationshipSelf            RelationshipStatus = "self"
 )
 
 // ConnectionWithUser represents a connection with user details
 type ConnectionWithUser struct {
 	Connection UserConnection `json:"connection"`
 	User       UserPublic     `json:"user"`
+	Tags       []string       `json:"tags,omitempty"` // The viewer's private tags, on the connections list only
+}
+
+// DisplayNameChange is one entry in a user's display name history
+type DisplayNameChange struct {
+	ID             uuid.UUID `json:"id"`
+	OldDisplayName string    `json:"old_display_name"`
+	NewDisplayName string    `json:"new_display_name"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// ConnectionTags is the full set of a user's private tags on one connection
+type ConnectionTags struct {
+	UserID uuid.UUID `json:"user_id"` // The tagged connection
+	Tags   []string  `json:"tags"`
+}
+
+// ConnectionTagCount is one of a user's connection tags and how many connections have it
+type ConnectionTagCount struct {
+	Tag         string `json:"tag"`
+	Connections int    `json:"connections"`
+}
+
+// ConnectionOverview is one page of each section of a user's network
+type ConnectionOverview struct {
+	Accepted ListResponse[ConnectionWithUser] `json:"accepted"`
+	Incoming ListResponse[ConnectionWithUser] `json:"incoming"`
+	Outgoing ListResponse[ConnectionWithUser] `json:"outgoing"`
+}
+
+// NewConnectionOverview returns an overview with three empty sections paginated by limit and offset
+func NewConnectionOverview(limit, offset int) *ConnectionOverview {
+	section := func() ListResponse[ConnectionWithUser] {
+		total := 0
+		return ListResponse[ConnectionWithUser]{
+			Data:       make([]ConnectionWithUser, 0),
+			Pagination: Pagination{Limit: limit, Offset: offset, Total: &total},
+		}
+	}
+	return &ConnectionOverview{Accepted: section(), Incoming: section(), Outgoing: section()}
+}
+
+// ConnectionCounts are the badge counts for a user's connections and pending requests
+type ConnectionCounts struct {
+	Connections     int `json:"connections"`
+	PendingIncoming int `json:"pending_incoming"`
+	PendingOutgoing int `json:"pending_outgoing"`
+}
+
+// ConnectionEvent records one status change of the connection between two users
+type ConnectionEvent struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	RequesterID uuid.UUID `json:"requester_id" db:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id" db:"addressee_id"`
+	Event       string    `json:"event" db:"event"`
+	ActorID     uuid.UUID `json:"actor_id" db:"actor_id"` // The user whose action caused the change
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Connection event types
+const (
+	ConnectionEventCreated  = "created"
+	ConnectionEventAccepted = "accepted"
+	ConnectionEventDeclined = "declined"
+	ConnectionEventRemoved  = "removed"
+)
+
+// InviteCode is a single-use code required to register when REGISTRATION_MODE is invite
+type InviteCode struct {
+	Code      string     `json:"code" db:"code"`
+	CreatedBy *uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	UsedBy    *uuid.UUID `json:"used_by" db:"used_by"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+}
+
+// Notification is an entry in a user's in-app notification center
+type Notification struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	UserID    uuid.UUID       `json:"-" db:"user_id"`
+	Type      string          `json:"type" db:"type"`
+	Payload   json.RawMessage `json:"payload" db:"payload"`
+	ReadAt    *time.Time      `json:"read_at" db:"read_at"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Session is a login session. The token itself is never stored or exposed,
+// only metadata about the device that created it.
+type Session struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	UserAgent string    `json:"user_agent" db:"user_agent"`
+	IPAddress string    `json:"ip_address" db:"ip_address"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	Current   bool      `json:"current"` // Whether this session made the request
 }
 
 // Request/Response DTOs
 type RegisterRequest struct {
 	Username    string `json:"username" binding:"required,min=3,max=30"`
-	DisplayName string `json:"display_name" binding:"required,min=1,max=100"`
-	Email       string `json:"email" binding:"required,email"`
+	DisplayName string `json:"display_name" binding:"required"`  // Validated after normalizing
+	Email       string `json:"email" binding:"required,max=254"` // Validated after normalizing
 	Password    string `json:"password" binding:"required,min=8"`
+	InviteCode  string `json:"invite_code"` // Required when REGISTRATION_MODE is invite
+}
+
+// CreateInviteCodeRequest optionally limits how long a new invite code stays valid
+type CreateInviteCodeRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" binding:"omitempty,min=1"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Identifier string `json:"identifier" binding:"required_without=Email"` // Email or username
+	Email      string `json:"email" binding:"omitempty,email"`             // Deprecated: use Identifier
+	Password   string `json:"password" binding:"required"`
+	Remember   bool   `json:"remember"` // Issue a longer-lived token
+}
+
+// SSOLoginRequest exchanges an identity provider token for a ConnectSphere token
+type SSOLoginRequest struct {
+	Token      string `json:"token" binding:"required"`
+	Remember   bool   `json:"remember"`
+	InviteCode string `json:"invite_code"` // Required to create an account when REGISTRATION_MODE is invite
 }
 
 type LoginResponse struct {
@@ -95,8 +364,130 @@ type LoginResponse struct {
 	User  UserAuth `json:"user"`
 }
 
+// UpdateProfileRequest has PATCH semantics: only non-nil fields are updated
 type UpdateProfileRequest struct {
-	DisplayName string `json:"display_name" binding:"required,min=1,max=100"`
+	DisplayName         *string `json:"display_name"` // Validated after normalizing
+	ProfileVisibility   *string `json:"profile_visibility" binding:"omitempty,oneof=public connections_only private"`
+	DiscoverableByEmail *bool   `json:"discoverable_by_email"`
+	// ConnectionRequestPolicy controls who may send new connection requests
+	ConnectionRequestPolicy *string `json:"connection_request_policy" binding:"omitempty,oneof=everyone connections_of_connections nobody"`
+	// ShowConnections lets the user's connections list their connections
+	ShowConnections *bool `json:"show_connections"`
+
+	// StatusMessage sets a short status shown to connections; "" clears it
+	StatusMessage *string `json:"status_message"`
+	// StatusExpiresIn clears the new status after this many seconds; omit to keep it until changed
+	StatusExpiresIn *int `json:"status_expires_in" binding:"omitempty,min=1"`
+
+	// ExpectedUpdatedAt, if set, must equal the profile's current updated_at
+	ExpectedUpdatedAt *time.Time `json:"expected_updated_at"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" binding:"required,max=254"` // Validated after normalizing
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+type BatchUsersRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100,dive,uuid"`
+}
+
+// BatchUsersResponse lists the requested users in request order, each once, in the
+// view the caller is allowed to see. IDs of missing or private users are in NotFound.
+type BatchUsersResponse struct {
+	Data     []any       `json:"data"`
+	NotFound []uuid.UUID `json:"not_found"`
+}
+
+type ConnectionStatusRequest struct {
+	UserIDs []string `json:"user_ids" binding:"required,min=1,max=100,dive,uuid"`
+}
+
+// ConnectionStatusResponse maps each requested user ID to the caller's relationship with them
+type ConnectionStatusResponse struct {
+	Statuses map[uuid.UUID]RelationshipStatus `json:"statuses"`
+}
+
+type IntrospectionRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// IntrospectionResponse describes a session token in RFC 7662 terms. Inactive
+// tokens carry only active: false.
+type IntrospectionResponse struct {
+	Active    bool       `json:"active"`
+	Subject   *uuid.UUID `json:"sub,omitempty"` // The user ID
+	Email     string     `json:"email,omitempty"`
+	ExpiresAt int64      `json:"exp,omitempty"` // Unix seconds
+	IssuedAt  int64      `json:"iat,omitempty"` // Unix seconds
+	SessionID *uuid.UUID `json:"jti,omitempty"`
+}
+
+// AdminStats is a snapshot of growth and activity for operators
+type AdminStats struct {
+	TotalUsers       int         `json:"total_users"`
+	TotalConnections int         `json:"total_connections"` // Accepted connections
+	ActiveUsers      ActiveUsers `json:"active_users"`
+	// SignupsPerDay covers the last 30 UTC days and SignupsPerWeek the last 12
+	// weeks (starting Monday), oldest first, including the current partial period
+	SignupsPerDay  []SignupCount `json:"signups_per_day"`
+	SignupsPerWeek []SignupCount `json:"signups_per_week"`
+	GeneratedAt    time.Time     `json:"generated_at"` // Stats may be served from a short-lived cache
+}
+
+// ActiveUsers counts distinct users who logged in during the last day, week and 30 days
+type ActiveUsers struct {
+	Day   int `json:"day"`
+	Week  int `json:"week"`
+	Month int `json:"month"`
+}
+
+// SignupCount is the number of users who registered in the period starting at Period
+type SignupCount struct {
+	Period  time.Time `json:"period"`
+	Signups int       `json:"signups"`
+}
+
+// Health statuses, from best to worst
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// HealthReport is the detailed health of the server and each dependency it checks.
+// Status is the worst of the components' statuses.
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]HealthComponent `json:"components"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+}
+
+// HealthComponent is the result of checking one dependency
+type HealthComponent struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	// Subscribers is the number of live event streams (hub only)
+	Subscribers *int `json:"subscribers,omitempty"`
+}
+
+type MaintenanceRequest struct {
+	ReadOnly *bool `json:"read_only" binding:"required"`
+}
+
+// MaintenanceStatus reports whether the API is in read-only maintenance mode
+type MaintenanceStatus struct {
+	ReadOnly bool `json:"read_only"`
+}
+
+// SetConnectionTagsRequest replaces a connection's tags; an empty list clears them
+type SetConnectionTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,max=100"`
+}
+
+type SendRequestByUsernameRequest struct {
+	Username string `json:"username" binding:"required"`
 }
 
 type ErrorResponse struct {
@@ -104,7 +495,61 @@ type ErrorResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// FieldError is one request field that failed validation
+type FieldError struct {
+	Field string `json:"field"`           // JSON name of the field
+	Rule  string `json:"rule"`            // Binding rule it broke, e.g. "required", "email" or "type"
+	Param string `json:"param,omitempty"` // Rule parameter, e.g. "3" for min=3
+}
+
+// ValidationFailedResponse is the 400 body returned when a request body fails binding validation
+type ValidationFailedResponse struct {
+	ErrorResponse
+	Details []FieldError `json:"details"`
+}
+
+// ConnectionExistsResponse is the 409 body returned when a connection between two users already exists
+type ConnectionExistsResponse struct {
+	ErrorResponse
+	Status    ConnectionStatus `json:"status"`    // Status of the existing connection
+	Direction string           `json:"direction"` // "outgoing" if the caller is the requester, otherwise "incoming"
+}
+
+// ConnectionLimitResponse is the 409 body returned when accepting a request would exceed
+// the connection cap
+type ConnectionLimitResponse struct {
+	ErrorResponse
+	UserID uuid.UUID `json:"user_id"` // The party that is at the limit
+}
+
 type SuccessResponse struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
+
+// Pagination describes which slice of a list a ListResponse holds.
+// Total is only set when it is cheap to compute.
+type Pagination struct {
+	Limit  int  `json:"limit"`
+	Offset int  `json:"offset"`
+	Total  *int `json:"total,omitempty"`
+}
+
+// ListResponse is the envelope returned by every list endpoint
+type ListResponse[T any] struct {
+	Data       []T        `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// NewListResponse builds a ListResponse for a fully loaded (unpaginated) list
+func NewListResponse[T any](data []T) ListResponse[T] {
+	total := len(data)
+	return ListResponse[T]{
+		Data: data,
+		Pagination: Pagination{
+			Limit:  total,
+			Offset: 0,
+			Total:  &total,
+		},
+	}
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxDisplayNameLength is the maximum display name length in characters (runes), not bytes
+const MaxDisplayNameLength = 100
+
+// zeroWidthJoiner is the one format character allowed in display names, since
+// emoji sequences such as families and professions are built with it
+const zeroWidthJoiner = '\u200d'
+
+// ValidationError is a field validation failure with a machine-readable code
+type ValidationError struct {
+	Code    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// usernamePattern matches the characters the frontend allows in usernames
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._]+$`)
+
+// ValidateUsername checks the username format and rejects reserved words (case-insensitive)
+func ValidateUsername(username string, reserved []string) error {
+	if !usernamePattern.MatchString(username) {
+		return &ValidationError{
+			Code:    "invalid_username",
+			Message: "Username can only contain letters, numbers, dots, and underscores",
+		}
+	}
+
+	for _, word := range reserved {
+		if strings.EqualFold(username, word) {
+			return &ValidationError{
+				Code:    "username_reserved",
+				Message: "This username is reserved",
+			}
+		}
+	}
+
+	return nil
+}
+
+// NormalizeDisplayName converts a display name to Unicode NFC so visually identical
+// names are stored, compared and length-checked the same way, and trims surrounding
+// whitespace so a name made only of spaces, tabs or newlines becomes empty
+func NormalizeDisplayName(displayName string) string {
+	return strings.TrimSpace(norm.NFC.String(displayName))
+}
+
+// ValidateDisplayName checks the length in characters and rejects invalid UTF-8,
+// control and invisible format characters, leading/trailing whitespace and runs
+// of consecutive whitespace. Callers should normalize with NormalizeDisplayName first.
+func ValidateDisplayName(displayName string) error {
+	if !utf8.ValidString(displayName) {
+		return &ValidationError{
+			Code:    "invalid_display_name",
+			Message: "Display name must be valid UTF-8",
+		}
+	}
+
+	if displayName == "" {
+		return &ValidationError{
+			Code:    "invalid_display_name",
+			Message: "Display name cannot be empty or only whitespace",
+		}
+	}
+
+	if length := utf8.RuneCountInString(displayName); length > MaxDisplayNameLength {
+		return &ValidationError{
+			Code:    "invalid_display_name",
+			Message: "Display name must be between 1 and 100 characters",
+		}
+	}
+
+	if strings.TrimSpace(displayName) != displayName {
+		return &ValidationError{
+			Code:    "invalid_display_name",
+			Message: "Display name cannot start or end with whitespace",
+		}
+	}
+
+	previousSpace := false
+	for _, r := range displayName {
+		if unicode.IsControl(r) {
+			return &ValidationError{
+				Code:    "invalid_display_name",
+				Message: "Display name cannot contain control characters",
+			}
+		}
+
+		if (unicode.Is(unicode.Cf, r) && r != zeroWidthJoiner) || unicode.Is(unicode.Co, r) {
+			return &ValidationError{
+				Code:    "invalid_display_name",
+				Message: "Display name cannot contain invisible or private-use characters",
+			}
+		}
+
+		isSpace := unicode.IsSpace(r)
+		if isSpace && previousSpace {
+			return &ValidationError{
+				Code:    "invalid_display_name",
+				Message: "Display name cannot contain consecutive whitespace",
+			}
+		}
+		previousSpace = isSpace
+	}
+
+	return nil
+}
+
+// MaxStatusMessageLength is the maximum status message length in characters
+const MaxStatusMessageLength = 100
+
+// NormalizeStatusMessage converts a status message to NFC and trims surrounding whitespace
+func NormalizeStatusMessage(status string) string {
+	return strings.TrimSpace(norm.NFC.String(status))
+}
+
+// ValidateStatusMessage checks the length in characters and rejects invalid UTF-8,
+// control, invisible format and private-use characters. An empty status is valid
+// and clears it. Callers should normalize with NormalizeStatusMessage first.
+func ValidateStatusMessage(status string) error {
+	if !utf8.ValidString(status) {
+		return &ValidationError{
+			Code:    "invalid_status_message",
+			Message: "Status message must be valid UTF-8",
+		}
+	}
+
+	if utf8.RuneCountInString(status) > MaxStatusMessageLength {
+		return &ValidationError{
+			Code:    "invalid_status_message",
+			Message: "Status message must be at most 100 characters",
+		}
+	}
+
+	for _, r := range status {
+		if unicode.IsControl(r) || (unicode.Is(unicode.Cf, r) && r != zeroWidthJoiner) || unicode.Is(unicode.Co, r) {
+			return &ValidationError{
+				Code:    "invalid_status_message",
+				Message: "Status message cannot contain control, invisible or private-use characters",
+			}
+		}
+	}
+
+	return nil
+}
+
+// MaxConnectionTagsPerConnection caps the tags a user can put on one connection
+const MaxConnectionTagsPerConnection = 10
+
+// tagPattern is the tag name format: lowercase letters, digits, - and _, up to 32 characters
+var tagPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// NormalizeTag trims and lowercases a connection tag, so "Close" and "close" are one tag
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// ValidateTag checks a normalized connection tag name
+func ValidateTag(tag string) error {
+	if !tagPattern.MatchString(tag) {
+		return &ValidationError{
+			Code:    "invalid_tag",
+			Message: "Tags must be 1-32 characters of lowercase letters, digits, '-' or '_'",
+		}
+	}
+	return nil
+}
+
+// ValidateEmail checks that a normalized email is a bare address such as
+// user@example.com, without a display name or angle brackets
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" || !strings.Contains(email[strings.LastIndex(email, "@")+1:], ".") {
+		return &ValidationError{
+			Code:    "invalid_email",
+			Message: "Email address is not valid",
+		}
+	}
+	return nil
+}
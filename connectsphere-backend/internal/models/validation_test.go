@@ -0,0 +1,193 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// validationCode returns the code of a *ValidationError, or "" for nil
+func validationCode(t *testing.T, err error) string {
+	t.Helper()
+
+	if err == nil {
+		return ""
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error %v is not a *ValidationError", err)
+	}
+	return verr.Code
+}
+
+func TestValidateDisplayName(t *testing.T) {
+	tests := []struct {
+		name        string
+		displayName string
+		valid       bool
+	}{
+		{"ascii", "Alice Smith", true},
+		{"100 ascii characters", strings.Repeat("a", 100), true},
+		{"101 ascii characters", strings.Repeat("a", 101), false},
+		// 400 bytes but 100 characters
+		{"100 emoji", strings.Repeat("😀", 100), true},
+		{"101 emoji", strings.Repeat("😀", 101), false},
+		{"100 CJK characters", strings.Repeat("名", 100), true},
+		{"accents", "José Müller", true},
+		{"right-to-left script", "مريم", true},
+		{"emoji joined with ZWJ", "👩\u200d👩\u200d👧 family", true},
+		{"invalid UTF-8", "bad\xffname", false},
+		{"NUL", "nul\x00name", false},
+		{"escape sequence", "\x1b[31mred", false},
+		{"zero-width space", "zero\u200bwidth", false},
+		{"right-to-left override", "evil\u202egnp.exe", false},
+		{"private use character", "private\ue000", false},
+		{"consecutive spaces", "Alice  Smith", false},
+		{"empty", "", false},
+		{"surrounding space", " Alice", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := validationCode(t, ValidateDisplayName(tt.displayName))
+			switch {
+			case tt.valid && code != "":
+				t.Fatalf("ValidateDisplayName(%q) = %s, want valid", tt.displayName, code)
+			case !tt.valid && code != "invalid_display_name":
+				t.Fatalf("ValidateDisplayName(%q) = %q, want invalid_display_name", tt.displayName, code)
+			}
+		})
+	}
+}
+
+func TestNormalizeDisplayName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"decomposed accent is composed", "Jose\u0301", "José"},
+		{"already composed", "José", "José"},
+		{"surrounding whitespace", "  Alice ", "Alice"},
+		{"tabs and newlines", "\tAlice\n", "Alice"},
+		{"only spaces", "   ", ""},
+		{"only tabs and newlines", "\t\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeDisplayName(tt.in); got != tt.want {
+				t.Fatalf("NormalizeDisplayName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	// Normalizing first means a decomposed name counts the same characters as its composed form
+	decomposed := strings.Repeat("e\u0301", 100)
+	if err := ValidateDisplayName(NormalizeDisplayName(decomposed)); err != nil {
+		t.Fatalf("100 decomposed accented characters rejected after normalizing: %v", err)
+	}
+}
+
+func TestValidateStatusMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		valid  bool
+	}{
+		{"empty clears the status", "", true},
+		{"text and emoji", "On holiday 🏖️", true},
+		{"100 emoji", strings.Repeat("😀", 100), true},
+		{"101 characters", strings.Repeat("a", 101), false},
+		{"invalid UTF-8", "\xff", false},
+		{"newline", "line\nbreak", false},
+		{"zero-width space", "a\u200bb", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := validationCode(t, ValidateStatusMessage(tt.status))
+			switch {
+			case tt.valid && code != "":
+				t.Fatalf("ValidateStatusMessage(%q) = %s, want valid", tt.status, code)
+			case !tt.valid && code != "invalid_status_message":
+				t.Fatalf("ValidateStatusMessage(%q) = %q, want invalid_status_message", tt.status, code)
+			}
+		})
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	reserved := []string{"admin", "support"}
+
+	tests := []struct {
+		username string
+		code     string
+	}{
+		{"alice", ""},
+		{"alice.smith_2", ""},
+		{"alice smith", "invalid_username"},
+		{"álice", "invalid_username"},
+		{"alice@example", "invalid_username"},
+		{"Admin", "username_reserved"},
+		{"admins", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			if code := validationCode(t, ValidateUsername(tt.username, reserved)); code != tt.code {
+				t.Fatalf("ValidateUsername(%q) = %q, want %q", tt.username, code, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		tag   string
+		valid bool
+	}{
+		{NormalizeTag("  Close-Friends "), true},
+		{"work_2024", true},
+		{strings.Repeat("a", 32), true},
+		{strings.Repeat("a", 33), false},
+		{"", false},
+		{"two words", false},
+		{"Upper", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if valid := ValidateTag(tt.tag) == nil; valid != tt.valid {
+				t.Fatalf("ValidateTag(%q) valid = %v, want %v", tt.tag, valid, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		valid bool
+	}{
+		{"user@example.com", true},
+		{"first.last+tag@sub.example.co.uk", true},
+		{"", false},
+		{"user", false},
+		{"user@", false},
+		{"@example.com", false},
+		{"user@localhost", false},
+		{"User <user@example.com>", false},
+		{"<user@example.com>", false},
+		{"user@example.com ", false},
+		{"two@at@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			if valid := ValidateEmail(tt.email) == nil; valid != tt.valid {
+				t.Fatalf("ValidateEmail(%q) valid = %v, want %v", tt.email, valid, tt.valid)
+			}
+		})
+	}
+}
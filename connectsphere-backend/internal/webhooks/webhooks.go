@@ -0,0 +1,139 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// queueSize bounds deliveries waiting for the worker; events beyond it are dropped
+const queueSize = 256
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret, prefixed with "sha256=".
+const (
+	SignatureHeader = "X-ConnectSphere-Signature"
+	TimestampHeader = "X-ConnectSphere-Timestamp"
+	EventHeader     = "X-ConnectSphere-Event"
+)
+
+// Payload is the JSON body POSTed to the webhook URL
+type Payload struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher delivers events to an outbound webhook in the background, retrying
+// failed deliveries with exponential backoff. A nil Dispatcher ignores events.
+type Dispatcher struct {
+	url         string
+	secret      []byte
+	events      map[string]bool
+	maxAttempts int
+	client      *http.Client
+	queue       chan Payload
+	sleep       func(time.Duration) // Waits out the retry backoff; replaced in tests
+}
+
+// NewDispatcher starts a delivery worker for url. Only the listed event types are sent.
+func NewDispatcher(url, secret string, events []string, maxAttempts int) *Dispatcher {
+	d := newDispatcher(url, secret, events, maxAttempts)
+	go d.run()
+	return d
+}
+
+// newDispatcher builds a Dispatcher without starting its worker
+func newDispatcher(url, secret string, events []string, maxAttempts int) *Dispatcher {
+	d := &Dispatcher{
+		url:         url,
+		secret:      []byte(secret),
+		events:      make(map[string]bool, len(events)),
+		maxAttempts: maxAttempts,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan Payload, queueSize),
+		sleep:       time.Sleep,
+	}
+	for _, event := range events {
+		d.events[event] = true
+	}
+	return d
+}
+
+// Send queues an event for delivery without blocking the caller
+func (d *Dispatcher) Send(eventType string, data interface{}) {
+	if d == nil || !d.events[eventType] {
+		return
+	}
+
+	payload := Payload{ID: uuid.New(), Type: eventType, CreatedAt: time.Now().UTC(), Data: data}
+	select {
+	case d.queue <- payload:
+	default:
+		log.Printf("Webhook queue full, dropping %s event %s", eventType, payload.ID)
+	}
+}
+
+// run delivers queued events one at a time
+func (d *Dispatcher) run() {
+	for payload := range d.queue {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			log.Printf("Failed to encode webhook %s event %s: %v", payload.Type, payload.ID, err)
+			continue
+		}
+
+		backoff := time.Second
+		for attempt := 1; ; attempt++ {
+			err := d.deliver(payload.Type, body)
+			if err == nil {
+				break
+			}
+			if attempt >= d.maxAttempts {
+				log.Printf("Giving up on webhook %s event %s after %d attempts: %v", payload.Type, payload.ID, attempt, err)
+				break
+			}
+			d.sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+// deliver POSTs one signed payload; any non-2xx response counts as a failure
+func (d *Dispatcher) deliver(eventType string, body []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	mac := hmac.New(sha256.New, d.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// delivery is one request received by the test endpoint
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestDispatcher starts a dispatcher for an endpoint that answers with the
+// given statuses in turn, then 200. It returns the dispatcher, the deliveries
+// the endpoint receives and the backoffs the dispatcher waits out.
+func newTestDispatcher(t *testing.T, events []string, maxAttempts int, statuses ...int) (*Dispatcher, chan delivery, chan time.Duration) {
+	t.Helper()
+
+	deliveries := make(chan delivery, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{header: r.Header.Clone(), body: body}
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	backoffs := make(chan time.Duration, 16)
+	d := newDispatcher(server.URL, "secret", events, maxAttempts)
+	d.sleep = func(backoff time.Duration) { backoffs <- backoff }
+	go d.run()
+	t.Cleanup(func() { close(d.queue) })
+
+	return d, deliveries, backoffs
+}
+
+// receive waits for the next delivery
+func receive(t *testing.T, deliveries chan delivery) delivery {
+	t.Helper()
+
+	select {
+	case got := <-deliveries:
+		return got
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a delivery")
+		return delivery{}
+	}
+}
+
+// expectNoDelivery checks nothing else reaches the endpoint
+func expectNoDelivery(t *testing.T, deliveries chan delivery) {
+	t.Helper()
+
+	select {
+	case got := <-deliveries:
+		t.Fatalf("unexpected delivery %s", got.body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDeliverySignature(t *testing.T) {
+	d, deliveries, _ := newTestDispatcher(t, []string{"connection_accepted"}, 1)
+	d.Send("connection_accepted", map[string]string{"user_id": "u1"})
+
+	got := receive(t, deliveries)
+	if event := got.header.Get(EventHeader); event != "connection_accepted" {
+		t.Fatalf("%s = %q, want connection_accepted", EventHeader, event)
+	}
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(got.header.Get(TimestampHeader) + "." + string(got.body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); got.header.Get(SignatureHeader) != want {
+		t.Fatalf("%s = %q, want %q", SignatureHeader, got.header.Get(SignatureHeader), want)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != "connection_accepted" || !reflect.DeepEqual(payload.Data, map[string]any{"user_id": "u1"}) {
+		t.Fatalf("payload = %+v", payload)
+	}
+}
+
+func TestSendFiltersEvents(t *testing.T) {
+	d, deliveries, _ := newTestDispatcher(t, []string{"connection_accepted"}, 1)
+
+	d.Send("connection_request", nil)
+	d.Send("connection_accepted", nil)
+
+	// Only the subscribed event is delivered
+	if got := receive(t, deliveries).header.Get(EventHeader); got != "connection_accepted" {
+		t.Fatalf("delivered %q, want connection_accepted", got)
+	}
+	expectNoDelivery(t, deliveries)
+
+	// A nil dispatcher (webhooks not configured) ignores events
+	var disabled *Dispatcher
+	disabled.Send("connection_accepted", nil)
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	t.Run("until success", func(t *testing.T) {
+		d, deliveries, backoffs := newTestDispatcher(t, []string{"connection_accepted"}, 5,
+			http.StatusInternalServerError, http.StatusBadGateway)
+		d.Send("connection_accepted", nil)
+
+		first := receive(t, deliveries)
+		receive(t, deliveries)
+		// The same payload is redelivered, and success ends the retries
+		if third := receive(t, deliveries); string(third.body) != string(first.body) {
+			t.Fatalf("retried body %s, want %s", third.body, first.body)
+		}
+		expectNoDelivery(t, deliveries)
+		if got := []time.Duration{<-backoffs, <-backoffs}; !reflect.DeepEqual(got, []time.Duration{time.Second, 2 * time.Second}) {
+			t.Fatalf("backoffs = %v, want [1s 2s]", got)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		d, deliveries, backoffs := newTestDispatcher(t, []string{"connection_accepted"}, 2,
+			http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+		d.Send("connection_accepted", nil)
+
+		receive(t, deliveries)
+		receive(t, deliveries)
+		expectNoDelivery(t, deliveries)
+		if len(backoffs) != 1 {
+			t.Fatalf("waited %d times, want once between the two attempts", len(backoffs))
+		}
+	})
+}
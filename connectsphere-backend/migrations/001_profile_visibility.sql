@@ -0,0 +1,4 @@
+-- Profile visibility modes (public, connections_only, private)
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS profile_visibility TEXT NOT NULL DEFAULT 'public'
+        CHECK (profile_visibility IN ('public', 'connections_only', 'private'));
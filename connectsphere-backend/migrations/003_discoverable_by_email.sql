@@ -0,0 +1,2 @@
+-- Opt-out of exact email lookup in search
+ALTER TABLE users ADD COLUMN IF NOT EXISTS discoverable_by_email BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,4 @@
+-- Admin accounts and the admin user listing's created_at filters and sorting
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
@@ -0,0 +1,8 @@
+-- Pending email changes (the old email stays active until the new one is confirmed)
+CREATE TABLE IF NOT EXISTS email_change_requests (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    new_email TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
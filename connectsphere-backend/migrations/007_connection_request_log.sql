@@ -0,0 +1,9 @@
+-- Every connection request ever sent, kept for rate limiting even after declines/removals
+CREATE TABLE IF NOT EXISTS connection_request_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    addressee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_connection_request_log_requester ON connection_request_log(requester_id, created_at);
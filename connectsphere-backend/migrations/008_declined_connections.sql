@@ -0,0 +1,5 @@
+-- Declined requests are kept for the re-send cooldown. The CHECK mirrors
+-- models.ConnectionStatus; it is replaced rather than altered, so re-running is safe.
+ALTER TABLE user_connections DROP CONSTRAINT IF EXISTS user_connections_status_check;
+ALTER TABLE user_connections ADD CONSTRAINT user_connections_status_check
+    CHECK (status IN ('pending', 'accepted', 'declined'));
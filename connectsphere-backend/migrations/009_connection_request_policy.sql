@@ -0,0 +1,4 @@
+-- Who may send the user new connection requests
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS connection_request_policy TEXT NOT NULL DEFAULT 'everyone'
+        CHECK (connection_request_policy IN ('everyone', 'connections_of_connections', 'nobody'));
@@ -0,0 +1,15 @@
+-- Case-insensitive identity lookups and connection side/status queries. The
+-- LOWER() unique indexes fail to build if two existing accounts differ only in
+-- the case of their email or username; resolve those rows first:
+--   SELECT LOWER(email), COUNT(*) FROM users GROUP BY 1 HAVING COUNT(*) > 1;
+--   SELECT LOWER(username), COUNT(*) FROM users GROUP BY 1 HAVING COUNT(*) > 1;
+DROP INDEX IF EXISTS idx_users_username;
+DROP INDEX IF EXISTS idx_users_email;
+DROP INDEX IF EXISTS idx_user_connections_requester;
+DROP INDEX IF EXISTS idx_user_connections_addressee;
+DROP INDEX IF EXISTS idx_user_connections_status;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users(LOWER(username));
+CREATE INDEX IF NOT EXISTS idx_user_connections_requester_status ON user_connections(requester_id, status);
+CREATE INDEX IF NOT EXISTS idx_user_connections_addressee_status ON user_connections(addressee_id, status);
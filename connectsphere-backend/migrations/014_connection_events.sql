@@ -0,0 +1,23 @@
+-- Append-only audit trail of connection status changes between two users. There are
+-- no foreign keys so the history survives the rows it describes.
+CREATE TABLE IF NOT EXISTS connection_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    requester_id UUID NOT NULL,
+    addressee_id UUID NOT NULL,
+    event TEXT NOT NULL CHECK (event IN ('created', 'accepted', 'declined', 'removed')),
+    actor_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_connection_events_pair ON connection_events(requester_id, addressee_id, created_at);
+
+CREATE OR REPLACE FUNCTION reject_connection_event_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'connection_events is append-only';
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS connection_events_append_only ON connection_events;
+CREATE TRIGGER connection_events_append_only BEFORE UPDATE OR DELETE ON connection_events
+    FOR EACH ROW EXECUTE FUNCTION reject_connection_event_changes();
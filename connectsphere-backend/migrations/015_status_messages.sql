@@ -0,0 +1,4 @@
+-- Profile status messages visible to connections
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status_message TEXT CHECK (char_length(status_message) BETWEEN 1 AND 100),
+    ADD COLUMN IF NOT EXISTS status_expires_at TIMESTAMPTZ; -- NULL keeps the status until it is changed
@@ -0,0 +1,8 @@
+-- Private tags (e.g. 'close') a user puts on their accepted connections
+CREATE TABLE IF NOT EXISTS connection_tags (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    connection_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tag VARCHAR(32) NOT NULL CHECK (tag ~ '^[a-z0-9_-]+$'),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, connection_user_id, tag)
+);
@@ -0,0 +1,2 @@
+-- Whether connections may list this user's connections
+ALTER TABLE users ADD COLUMN IF NOT EXISTS show_connections BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,2 @@
+-- Active user counts in the admin stats
+CREATE INDEX IF NOT EXISTS idx_sessions_created_at ON sessions(created_at);
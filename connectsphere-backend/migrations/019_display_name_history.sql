@@ -0,0 +1,10 @@
+-- Previous display names, kept for moderation and to rate-limit changes
+CREATE TABLE IF NOT EXISTS display_name_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_display_name TEXT NOT NULL,
+    new_display_name TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_display_name_history_user ON display_name_history(user_id, changed_at DESC);
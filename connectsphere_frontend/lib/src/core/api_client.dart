@@ -114,7 +114,7 @@ class ApiClient {
         '/users/search',
         queryParameters: {'q': query, 'limit': limit},
       );
-      return (response.data as List)
+      return (response.data['data'] as List)
           .map((json) => User.fromJson(json))
           .toList();
     } on DioException catch (e) {
@@ -158,7 +158,7 @@ class ApiClient {
   Future<List<ConnectionWithUser>> getConnections() async {
     try {
       final response = await _dio.get('/connections');
-      return (response.data as List)
+      return (response.data['data'] as List)
           .map((json) => ConnectionWithUser.fromJson(json))
           .toList();
     } on DioException catch (e) {
@@ -169,7 +169,7 @@ class ApiClient {
   Future<List<ConnectionWithUser>> getPendingRequests() async {
     try {
       final response = await _dio.get('/connections/pending');
-      return (response.data as List)
+      return (response.data['data'] as List)
           .map((json) => ConnectionWithUser.fromJson(json))
           .toList();
     } on DioException catch (e) {
@@ -118,7 +118,12 @@ class _ProfileScreenState extends ConsumerState<ProfileScreen> {
               const SizedBox(height: 16),
 
               // Member since
-              _buildInfoCard('Member Since', _formatDate(user.createdAt)),
+              _buildInfoCard(
+                'Member Since',
+                user.createdAt != null
+                    ? _formatDate(user.createdAt!)
+                    : 'Not available',
+              ),
 
               const SizedBox(height: 32),
 
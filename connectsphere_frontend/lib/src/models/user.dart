@@ -10,15 +10,16 @@ class User extends Equatable {
   @JsonKey(name: 'display_name')
   final String displayName;
   final String? email; // Optional since search results don't include email
+  // Hidden for connections_only profiles the viewer isn't connected with
   @JsonKey(name: 'created_at')
-  final DateTime createdAt;
+  final DateTime? createdAt;
 
   const User({
     required this.id,
     required this.username,
     required this.displayName,
     this.email, // Optional
-    required this.createdAt,
+    this.createdAt,
   });
 
   factory User.fromJson(Map<String, dynamic> json) => _$UserFromJson(json);
@@ -56,6 +57,9 @@ class UserConnection extends Equatable {
   final DateTime createdAt;
   @JsonKey(name: 'updated_at')
   final DateTime updatedAt;
+  // When the request was accepted; only set for accepted connections
+  @JsonKey(name: 'connected_at')
+  final DateTime? connectedAt;
 
   const UserConnection({
     required this.id,
@@ -64,6 +68,7 @@ class UserConnection extends Equatable {
     required this.status,
     required this.createdAt,
     required this.updatedAt,
+    this.connectedAt,
   });
 
   factory UserConnection.fromJson(Map<String, dynamic> json) =>
@@ -78,6 +83,7 @@ class UserConnection extends Equatable {
         status,
         createdAt,
         updatedAt,
+        connectedAt,
       ];
 }
 
@@ -14,27 +14,27 @@ TOKEN="eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ1c2VyX2lkIjoiYzc1NTMxMGMtNDA4ZC00
 echo ""
 echo "Test 1: Search for 'john' (should find john_doe)"
 curl -s -X GET "${BASE_URL}/users/search?q=john&limit=10" \
-  -H "Authorization: Bearer ${TOKEN}" | jq '.[].username'
+  -H "Authorization: Bearer ${TOKEN}" | jq '.data[].username'
 
 echo ""
 echo "Test 2: Search for 'doe' (should find john_doe by display name)"
 curl -s -X GET "${BASE_URL}/users/search?q=doe&limit=10" \
-  -H "Authorization: Bearer ${TOKEN}" | jq '.[].display_name'
+  -H "Authorization: Bearer ${TOKEN}" | jq '.data[].display_name'
 
 echo ""
 echo "Test 3: Search for 'jane' (should find jane_smith)"
 curl -s -X GET "${BASE_URL}/users/search?q=jane&limit=10" \
-  -H "Authorization: Bearer ${TOKEN}" | jq '.[].username'
+  -H "Authorization: Bearer ${TOKEN}" | jq '.data[].username'
 
 echo ""
 echo "Test 4: Search for 'smith' (should find jane_smith by display name)"
 curl -s -X GET "${BASE_URL}/users/search?q=smith&limit=10" \
-  -H "Authorization: Bearer ${TOKEN}" | jq '.[].display_name'
+  -H "Authorization: Bearer ${TOKEN}" | jq '.data[].display_name'
 
 echo ""
 echo "Test 5: Partial search 'j' (should find both john and jane, ranked appropriately)"
 curl -s -X GET "${BASE_URL}/users/search?q=j&limit=10" \
-  -H "Authorization: Bearer ${TOKEN}" | jq '.[] | {username: .username, display_name: .display_name}'
+  -H "Authorization: Bearer ${TOKEN}" | jq '.data[] | {username: .username, display_name: .display_name}'
 
 echo ""
 echo "✅ Search functionality testing complete!"